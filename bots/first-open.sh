@@ -0,0 +1,5 @@
+#!/bin/sh
+# Example external bot: reads the state line and plays the first open square.
+# The line is "<state>" on 3x3 boards and "<rows> <cols> <k> <state>" otherwise.
+read -r line
+echo "$line" | awk '{ s = $NF; n = length(s) - 1; print tolower(substr(s, n + 1, 1)) (index(substr(s, 1, n), ".") - 1) }'
@@ -0,0 +1,288 @@
+// Command arena plays every registered bot against every other bot and
+// reports win/draw/loss matrices and Elo estimates.
+//
+// Usage:
+//
+//	go run ./cmd/arena -games 20 -bots easy,medium,perfect -json
+//	go run ./cmd/arena -rows 7 -cols 7 -k 4 -bots heuristic,medium
+//	go run ./cmd/arena -mode ultimate
+//	go run ./cmd/arena -variant misere
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	tttService "github.com/Maiar0/tictactoe_backend/internal/tictactoe/service"
+	tttStore "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
+)
+
+// record counts results from the row bot's point of view.
+type record struct {
+	Wins     int `json:"wins"`
+	Draws    int `json:"draws"`
+	Losses   int `json:"losses"`
+	Forfeits int `json:"forfeits"`
+}
+
+type report struct {
+	Board   string                       `json:"board"`
+	Games   int                          `json:"gamesPerPairing"`
+	Bots    []string                     `json:"bots"`
+	Results map[string]map[string]record `json:"results"`
+	Totals  map[string]record            `json:"totals"`
+	Elo     map[string]float64           `json:"elo"`
+}
+
+// game is one finished game used for the Elo fit.
+type game struct {
+	x, o  string
+	score float64 // 1 if x won, 0.5 draw, 0 if o won
+}
+
+func main() {
+	games := flag.Int("games", 10, "games per pairing; sides alternate so each bot plays X in half")
+	botList := flag.String("bots", "", "comma separated bot names (default: every registered bot)")
+	botsDir := flag.String("bots-dir", "bots", "directory of external bot executables to register")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	verbose := flag.Bool("v", false, "keep service logging")
+	rows := flag.Int("rows", 3, "board rows")
+	cols := flag.Int("cols", 3, "board columns")
+	k := flag.Int("k", 3, "pieces in a row needed to win")
+	mode := flag.String("mode", tttService.ModeClassic, "game mode: "+strings.Join(tttService.Modes, ", "))
+	variant := flag.String("variant", tttService.VariantStandard, "rule variant: standard, misere, wild or notakto")
+	flag.Parse()
+
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+	if err := tttService.LoadExternalBots(*botsDir); err != nil {
+		fmt.Fprintln(os.Stderr, "arena: failed to load external bots:", err)
+		os.Exit(1)
+	}
+	board := tttService.Board{Rows: *rows, Cols: *cols, K: *k}
+	if err := board.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "arena:", err)
+		os.Exit(1)
+	}
+	if !tttService.IsMode(*mode) {
+		fmt.Fprintln(os.Stderr, "arena: unknown mode:", *mode)
+		os.Exit(1)
+	}
+	if !tttService.IsVariant(*variant) {
+		fmt.Fprintln(os.Stderr, "arena: unknown variant:", *variant)
+		os.Exit(1)
+	}
+	config := tttStore.GameConfig{Mode: *mode, Variant: *variant, Rows: board.Rows, Cols: board.Cols, K: board.K}
+	var names []string
+	if *botList != "" {
+		names = strings.Split(*botList, ",")
+	} else {
+		for _, name := range tttService.BotNames() {
+			if bot, _ := tttService.GetBot(name); tttService.BotSupports(bot, *mode, *variant) {
+				names = append(names, name)
+			}
+		}
+	}
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] {
+			fmt.Fprintln(os.Stderr, "arena: bot listed twice:", name)
+			os.Exit(1)
+		}
+		seen[name] = true
+		bot, ok := tttService.GetBot(name)
+		if !ok {
+			fmt.Fprintln(os.Stderr, "arena: unknown bot:", name)
+			os.Exit(1)
+		}
+		if !tttService.BotSupports(bot, *mode, *variant) {
+			fmt.Fprintln(os.Stderr, "arena: bot", name, "cannot play", *mode, *variant)
+			os.Exit(1)
+		}
+	}
+
+	boardName := fmt.Sprintf("%dx%d k=%d", board.Rows, board.Cols, board.K)
+	if *mode != tttService.ModeClassic {
+		boardName = *mode
+	}
+	if *variant != tttService.VariantStandard {
+		boardName += " " + *variant
+	}
+	rep := report{Board: boardName, Games: *games, Bots: names, Results: map[string]map[string]record{}, Totals: map[string]record{}}
+	var played []game
+	for _, a := range names {
+		rep.Results[a] = map[string]record{}
+	}
+	for i, a := range names {
+		for _, b := range names[i+1:] {
+			for n := 0; n < *games; n++ {
+				x, o := a, b
+				if n%2 == 1 {
+					x, o = b, a
+				}
+				botX, _ := tttService.GetBot(x)
+				botO, _ := tttService.GetBot(o)
+				result := tttService.PlayMatch(config, botX, botO)
+				g := game{x: x, o: o, score: 0.5}
+				switch result.Winner {
+				case 'x':
+					g.score = 1
+				case 'o':
+					g.score = 0
+				}
+				played = append(played, g)
+				tally(rep.Results, x, o, g.score, result.Forfeit)
+			}
+		}
+	}
+	for _, a := range names {
+		var total record
+		for _, r := range rep.Results[a] {
+			total.Wins += r.Wins
+			total.Draws += r.Draws
+			total.Losses += r.Losses
+			total.Forfeits += r.Forfeits
+		}
+		rep.Totals[a] = total
+	}
+	rep.Elo = estimateElo(names, played)
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(rep); err != nil {
+			fmt.Fprintln(os.Stderr, "arena:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	printTables(rep)
+}
+
+// tally records one game for both bots.
+func tally(results map[string]map[string]record, x, o string, score float64, forfeit bool) {
+	rx, ro := results[x][o], results[o][x]
+	switch score {
+	case 1:
+		rx.Wins++
+		ro.Losses++
+		if forfeit {
+			ro.Forfeits++
+		}
+	case 0:
+		ro.Wins++
+		rx.Losses++
+		if forfeit {
+			rx.Forfeits++
+		}
+	default:
+		rx.Draws++
+		ro.Draws++
+	}
+	results[x][o], results[o][x] = rx, ro
+}
+
+// Elo fitting stops once no rating moves by more than eloTolerance in a
+// pass, or after eloMaxIterations passes.
+const (
+	eloTolerance     = 0.01
+	eloMaxIterations = 10000
+)
+
+// estimateElo fits ratings to all games by repeatedly nudging each rating
+// towards the score it actually achieved. Every pairing also gets one
+// virtual draw, so a bot that won every game ends up a finite distance
+// ahead instead of wherever the fit happened to stop. Ratings are centred
+// on 1500.
+func estimateElo(names []string, played []game) map[string]float64 {
+	ratings := map[string]float64{}
+	for _, name := range names {
+		ratings[name] = 1500
+	}
+	if len(played) == 0 {
+		return ratings
+	}
+	games := append([]game(nil), played...)
+	paired := map[[2]string]bool{}
+	for _, g := range played {
+		pair := [2]string{g.x, g.o}
+		if pair[0] > pair[1] {
+			pair[0], pair[1] = pair[1], pair[0]
+		}
+		if !paired[pair] {
+			paired[pair] = true
+			games = append(games, game{x: pair[0], o: pair[1], score: 0.5})
+		}
+	}
+	expected := func(a, b string) float64 {
+		return 1 / (1 + math.Pow(10, (ratings[b]-ratings[a])/400))
+	}
+	for iter := 0; iter < eloMaxIterations; iter++ {
+		delta := map[string]float64{}
+		count := map[string]int{}
+		for _, g := range games {
+			e := expected(g.x, g.o)
+			delta[g.x] += g.score - e
+			delta[g.o] -= g.score - e
+			count[g.x]++
+			count[g.o]++
+		}
+		largest := 0.0
+		for _, name := range names {
+			if count[name] > 0 {
+				step := 128 * delta[name] / float64(count[name])
+				ratings[name] += step
+				largest = math.Max(largest, math.Abs(step))
+			}
+		}
+		if largest < eloTolerance {
+			break
+		}
+	}
+	mean := 0.0
+	for _, r := range ratings {
+		mean += r
+	}
+	mean /= float64(len(ratings))
+	for name := range ratings {
+		ratings[name] = math.Round(ratings[name] - mean + 1500)
+	}
+	return ratings
+}
+
+func printTables(rep report) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "W/D/L\t%s\n", strings.Join(rep.Bots, "\t"))
+	for _, a := range rep.Bots {
+		row := []string{a}
+		for _, b := range rep.Bots {
+			if a == b {
+				row = append(row, "-")
+				continue
+			}
+			r := rep.Results[a][b]
+			row = append(row, fmt.Sprintf("%d/%d/%d", r.Wins, r.Draws, r.Losses))
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+	fmt.Println()
+
+	ranked := append([]string(nil), rep.Bots...)
+	sort.Slice(ranked, func(i, j int) bool { return rep.Elo[ranked[i]] > rep.Elo[ranked[j]] })
+	fmt.Fprintln(w, "Bot\tElo\tWins\tDraws\tLosses\tForfeits")
+	for _, name := range ranked {
+		t := rep.Totals[name]
+		fmt.Fprintf(w, "%s\t%.0f\t%d\t%d\t%d\t%d\n", name, rep.Elo[name], t.Wins, t.Draws, t.Losses, t.Forfeits)
+	}
+	w.Flush()
+}
@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	_ "modernc.org/sqlite" // Registers the SQLite driver with database/sql
 )
@@ -18,11 +20,15 @@ func New(base string) *Store {
 	return &Store{BaseDir: base}
 }
 
+// busyTimeout makes a connection wait this long (ms) for another one to
+// finish with the file instead of failing with SQLITE_BUSY.
+const busyTimeout = 5000
+
 // OpenFor opens (or creates) a SQLite DB file for the given game ID
 // and ensures the schema is applied from the provided schema file.
 func (s *Store) OpenFor(gameID string, schemaPath string) (*sql.DB, error) {
 	path := filepath.Join(s.BaseDir, gameID+".db")
-	db, err := sql.Open("sqlite", path)
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout("+strconv.Itoa(busyTimeout)+")")
 	if err != nil {
 		return nil, err
 	}
@@ -33,6 +39,25 @@ func (s *Store) OpenFor(gameID string, schemaPath string) (*sql.DB, error) {
 	return db, nil
 }
 
+// Exists reports whether the given game ID has a DB file, without creating one.
+func (s *Store) Exists(gameID string) bool {
+	_, err := os.Stat(filepath.Join(s.BaseDir, gameID+".db"))
+	return err == nil
+}
+
+// GameIDs lists the games that have a DB file in the base directory.
+func (s *Store) GameIDs() ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(s.BaseDir, "*.db"))
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(paths))
+	for _, path := range paths {
+		ids = append(ids, strings.TrimSuffix(filepath.Base(path), ".db"))
+	}
+	return ids, nil
+}
+
 // ensureSchema reads the SQL schema file at the given path and executes it
 // against the provided database connection.
 func ensureSchema(db *sql.DB, path string) error {
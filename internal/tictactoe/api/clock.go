@@ -0,0 +1,81 @@
+package api
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	tttService "github.com/Maiar0/tictactoe_backend/internal/tictactoe/service"
+	tttStore "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
+)
+
+// clockTimers holds the timer that ends each timed game when the side to move flags.
+var (
+	clockTimers   = make(map[string]*time.Timer)
+	clockTimersMu sync.Mutex
+)
+
+// watchClock reads the game's clock and arms a timer for the side to move,
+// replacing any earlier one. Returns nil for games without a running clock.
+func watchClock(gameID string, gameState tttStore.GameState) *tttService.Clock {
+	clock := currentClock(gameID, gameState)
+	if clock == nil {
+		return nil
+	}
+	clockTimersMu.Lock()
+	defer clockTimersMu.Unlock()
+	if timer, exists := clockTimers[gameID]; exists {
+		timer.Stop()
+		delete(clockTimers, gameID)
+	}
+	//correspondence deadlines are left to the scheduler, which survives restarts
+	if clock.Deadline == 0 || gameState.Config.Correspondence {
+		return clock
+	}
+	//one second late so the flag has certainly fallen at the stored resolution
+	wait := time.Until(time.Unix(clock.Deadline+1, 0))
+	clockTimers[gameID] = time.AfterFunc(wait, func() { flagGame(gameID) })
+	log.Println("[watchClock] Game ", gameID, " side ", clock.Turn, " flags in ", wait)
+	return clock
+}
+
+// StartCorrespondence starts the scheduler that enforces correspondence
+// deadlines, sending reminders through notifier and telling the game group
+// when a game is forfeited.
+func StartCorrespondence(notifier tttService.Notifier) (stop func()) {
+	scheduler := tttService.NewScheduler(notifier)
+	scheduler.OnForfeit = func(gameID string, gameState tttStore.GameState) {
+		winner, _ := tttService.ParseStatus(gameState.Status)
+		announceEnd(gameID, gameState, tttService.EventTimeout, tttService.Opponent(gameState, winner))
+	}
+	return scheduler.Start()
+}
+
+// currentClock reads the game's clock without touching its timer.
+// Returns nil for games without a running clock.
+func currentClock(gameID string, gameState tttStore.GameState) *tttService.Clock {
+	if !tttService.HasTimeControl(gameState.Config) {
+		return nil
+	}
+	clock, ok, err := tttService.GetClock(gameID, time.Now().Unix())
+	if err != nil || !ok {
+		return nil
+	}
+	return &clock
+}
+
+// flagGame ends the game if the side to move is out of time and tells the game group.
+// Returns true if the game was ended.
+func flagGame(gameID string) bool {
+	gameState, flagged, err := tttService.TimeOut(gameID, time.Now().Unix())
+	if err != nil {
+		log.Println("[flagGame] Failed to check clock: ", err)
+		return false
+	}
+	if !flagged {
+		return false
+	}
+	winner, _ := tttService.ParseStatus(gameState.Status)
+	announceEnd(gameID, gameState, tttService.EventTimeout, tttService.Opponent(gameState, winner))
+	return true
+}
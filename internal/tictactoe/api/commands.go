@@ -0,0 +1,224 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	tttService "github.com/Maiar0/tictactoe_backend/internal/tictactoe/service"
+	tttStore "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
+)
+
+// Websocket commands mirror the REST endpoints of the same name and share
+// their validation, so a client can play over a single connection. Each one
+// is answered on the requesting connection, echoing the request ID.
+
+// requestErrorCode maps the HTTP status of a rejected request to a protocol error code.
+func requestErrorCode(status int) string {
+	switch {
+	case status == http.StatusBadRequest:
+		return ErrBadRequest
+	case status == http.StatusNotFound:
+		return ErrNotFound
+	case status >= http.StatusInternalServerError:
+		return ErrInternal
+	default:
+		return ErrRejected
+	}
+}
+
+// decodeCommand reads a command's payload into req, replying with an error if it can't.
+func decodeCommand(c *client, cmd command, req any) bool {
+	if err := json.Unmarshal(cmd.Payload, req); err != nil {
+		replyError(c, cmd, ErrBadRequest, "Invalid payload: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// wsCreateGame creates a game and puts the creator in its group.
+func wsCreateGame(c *client, cmd command) {
+	var req newGameReq
+	if !decodeCommand(c, cmd, &req) {
+		return
+	}
+	gameID, reqErr := createGame(req)
+	if reqErr != nil {
+		replyError(c, cmd, requestErrorCode(reqErr.Status), reqErr.Message)
+		return
+	}
+	addClient(c, req.PlayerUUID)
+	addPlayerToGame(req.PlayerUUID, gameID)
+	reply(c, cmd, "game_created", newGameResp{GameID: gameID})
+	log.Println("[wsCreateGame] Game created successfully with ID: ", gameID)
+}
+
+// currentGame reads the latest state of the game a command is about.
+func currentGame(gameID string) (tttStore.GameState, *requestError) {
+	gameState, err := tttStore.GetGameState(gameID)
+	if errors.Is(err, tttStore.ErrGameNotFound) {
+		return tttStore.GameState{}, rejectRequest(http.StatusNotFound, "Game not found.")
+	}
+	if err != nil {
+		return tttStore.GameState{}, rejectRequest(http.StatusInternalServerError, "Failed to get game state.")
+	}
+	return gameState, nil
+}
+
+// wsGameAction runs one of the takeback, draw, resign or rematch actions for
+// the command's player and answers the command with replyType once the
+// game has been told, or with the error the action failed with.
+func wsGameAction(c *client, cmd command, replyType string, action func(playerUUID, gameID string) *requestError) {
+	if reqErr := action(cmd.Request.PlayerID, cmd.Request.GameID); reqErr != nil {
+		replyError(c, cmd, requestErrorCode(reqErr.Status), reqErr.Message)
+		return
+	}
+	reply(c, cmd, replyType, nil)
+}
+
+// wsJoinGame puts a seated player in their game's group. Anyone without a
+// seat watches the game as a spectator instead.
+func wsJoinGame(c *client, cmd command) {
+	playerUUID, gameID := cmd.Request.PlayerID, cmd.Request.GameID
+	if gameID == "" {
+		replyError(c, cmd, ErrBadRequest, "gameId is required.")
+		return
+	}
+	gameState, reqErr := currentGame(gameID)
+	if reqErr != nil {
+		replyError(c, cmd, requestErrorCode(reqErr.Status), reqErr.Message)
+		return
+	}
+	if playerUUID != gameState.PlayerX && playerUUID != gameState.PlayerO {
+		addClient(c, playerUUID)
+		reply(c, cmd, "spectating", nil)
+		addSpectatorToGame(playerUUID, gameID)
+		pushSnapshot(c, playerUUID, gameID)
+		return
+	}
+	addPlayerToGame(playerUUID, gameID)
+	reply(c, cmd, "joined_game", nil)
+	pushSnapshot(c, playerUUID, gameID)
+}
+
+// wsChooseSide seats the player and puts them in the game's group.
+func wsChooseSide(c *client, cmd command) {
+	var req choosePlayerReq
+	if !decodeCommand(c, cmd, &req) {
+		return
+	}
+	if req.PlayerUUID != "" && req.GameID != "" {
+		//join first so the broadcast of the seated state reaches this client
+		addClient(c, req.PlayerUUID)
+		addPlayerToGame(req.PlayerUUID, req.GameID)
+	}
+	gameState, reqErr := chooseSide(req)
+	if reqErr != nil {
+		replyError(c, cmd, requestErrorCode(reqErr.Status), reqErr.Message)
+		return
+	}
+	reply(c, cmd, "side_chosen", choosePlayerResp{GameState: gameState.State, Result: tttService.Result(gameState)})
+}
+
+// wsMove plays a move; the game group also gets the usual broadcast.
+func wsMove(c *client, cmd command) {
+	var req makeMoveReq
+	if !decodeCommand(c, cmd, &req) {
+		return
+	}
+	gameState, reqErr := playMove(req)
+	if reqErr != nil {
+		replyError(c, cmd, requestErrorCode(reqErr.Status), reqErr.Message)
+		return
+	}
+	reply(c, cmd, "move_made", makeMoveResp{GameState: gameState.State, Result: tttService.Result(gameState), Clock: currentClock(req.GameID, gameState)})
+}
+
+// wsGetGameState answers with the same state as the state endpoint.
+func wsGetGameState(c *client, cmd command) {
+	resp, reqErr := gameStateFor(getGameStateReq{PlayerUUID: cmd.Request.PlayerID, GameID: cmd.Request.GameID})
+	if reqErr != nil {
+		replyError(c, cmd, requestErrorCode(reqErr.Status), reqErr.Message)
+		return
+	}
+	reply(c, cmd, "game_state", resp)
+}
+
+// pushSnapshot sends a client the full state of a game it just registered
+// for, so a reconnecting client is in sync without asking.
+func pushSnapshot(c *client, playerUUID, gameID string) {
+	if gameID == "" || !tttStore.Exists(gameID) {
+		return
+	}
+	resp, reqErr := gameStateFor(getGameStateReq{PlayerUUID: playerUUID, GameID: gameID})
+	if reqErr != nil {
+		log.Println("[pushSnapshot] No snapshot for game ", gameID, ": ", reqErr.Message)
+		return
+	}
+	sendToConn(c, serverMessage{Type: "game_state", Payload: resp, Legacy: resp})
+}
+
+// ResumeRequest is the payload of resume: the client's player and game and
+// the seq and epoch of the last broadcast it saw, 0 and "" if none.
+type ResumeRequest struct {
+	PlayerID string `json:"playerId"`
+	GameID   string `json:"gameId"`
+	LastSeq  int64  `json:"lastSeq"`
+	Epoch    string `json:"epoch"`
+}
+
+// resyncEvent replaces missed broadcasts that are no longer buffered: the
+// game as stored, with every move so far. Broadcasts after its seq follow.
+type resyncEvent struct {
+	GameID string                   `json:"gameId"`
+	State  getGameStateResp         `json:"state"`
+	Moves  []tttService.HistoryMove `json:"moves"`
+}
+
+type resumeResp struct {
+	GameID   string `json:"gameId"`
+	Seq      int64  `json:"seq"`      // game's seq once the replay was queued
+	Epoch    string `json:"epoch"`    // epoch the seq belongs to
+	Replayed int    `json:"replayed"` // buffered broadcasts sent again
+	Resynced bool   `json:"resynced"` // a resync was sent because the buffer fell short or the epoch changed
+}
+
+// wsResume puts a reconnecting player back in their game and sends what
+// they missed while disconnected.
+func wsResume(c *client, cmd command) {
+	var req ResumeRequest
+	if !decodeCommand(c, cmd, &req) {
+		return
+	}
+	if req.GameID == "" {
+		replyError(c, cmd, ErrBadRequest, "gameId is required.")
+		return
+	}
+	//load the resync before joining; broadcasts after resyncSeq are replayed
+	//on top of it, so nothing falls between the two
+	resyncSeq := hub.Seq(req.GameID)
+	state, reqErr := gameStateFor(getGameStateReq{PlayerUUID: req.PlayerID, GameID: req.GameID})
+	if reqErr != nil {
+		replyError(c, cmd, requestErrorCode(reqErr.Status), reqErr.Message)
+		return
+	}
+	rows, err := tttStore.GetGameHistory(req.GameID)
+	if err != nil {
+		replyError(c, cmd, ErrInternal, "Failed to get game history.")
+		return
+	}
+	moves := tttService.History(rows)
+	if moves == nil {
+		moves = []tttService.HistoryMove{}
+	}
+	resync := resyncEvent{GameID: req.GameID, State: state, Moves: moves}
+	out, err := encode(serverMessage{Type: "resync", Payload: resync, Legacy: resync})
+	if err != nil {
+		replyError(c, cmd, ErrInternal, "Failed to encode resync.")
+		return
+	}
+	replayed, resynced, seq := hub.Resume(c, req.PlayerID, req.GameID, req.Epoch, req.LastSeq, out, resyncSeq)
+	log.Println("[wsResume] Player ", req.PlayerID, " resumed game ", req.GameID, " from seq ", req.LastSeq, ": replayed ", replayed, " resynced ", resynced)
+	reply(c, cmd, "resumed", resumeResp{GameID: req.GameID, Seq: seq, Epoch: hub.epoch, Replayed: replayed, Resynced: resynced})
+}
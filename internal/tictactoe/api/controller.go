@@ -1,9 +1,10 @@
 package api
 
 import (
-	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 
 	tttService "github.com/Maiar0/tictactoe_backend/internal/tictactoe/service"
@@ -14,6 +15,18 @@ import (
 type newGameReq struct {
 	PlayerUUID string `json:"playerId"`
 	IsAi       bool   `json:"isAi"`
+	AILevel    string `json:"aiLevel"`   // random, easy, medium or perfect; defaults to medium
+	Bot        string `json:"bot"`       // any registered bot name; takes precedence over aiLevel
+	Rows       int    `json:"rows"`      // board rows; defaults to 3
+	Cols       int    `json:"cols"`      // board columns; defaults to 3
+	K          int    `json:"k"`         // pieces in a row needed to win; defaults to 3
+	Mode       string `json:"mode"`      // classic, ultimate or qubic; defaults to classic
+	Variant    string `json:"variant"`   // standard, misere, wild or notakto; defaults to standard
+	MoveTime   int    `json:"moveTime"`  // seconds per move; 0 for no limit
+	BaseTime   int    `json:"baseTime"`  // seconds per side for the whole game; 0 for no limit
+	Increment  int    `json:"increment"` // seconds added after each move with baseTime
+	MoveHours  int    `json:"moveHours"` // hours per move for a correspondence game; replaces the other times
+	BestOf     int    `json:"bestOf"`    // odd series length played out through rematches; 0 for a single game
 }
 type newGameResp struct {
 	GameID string `json:"gameId"`
@@ -31,24 +44,94 @@ func newGame(w http.ResponseWriter, r *http.Request) {
 		utils.WriteJSONError(w, http.StatusBadRequest, "Bad Request")
 		return
 	}
+	id, reqErr := createGame(req)
+	if reqErr != nil {
+		utils.WriteJSONError(w, reqErr.Status, reqErr.Message)
+		return
+	}
+	//write response
+	utils.WriteJSONResponse(w, http.StatusCreated, newGameResp{GameID: id})
+	log.Println("[newGame] Game created successfully with ID: ", id)
+}
+
+// requestError is a rejected request, with the HTTP status and message to
+// report. The websocket commands map Status to a protocol error code.
+type requestError struct {
+	Status  int
+	Message string
+}
+
+func rejectRequest(status int, message string) *requestError {
+	return &requestError{Status: status, Message: message}
+}
+
+// createGame validates a new game request and creates the game.
+func createGame(req newGameReq) (string, *requestError) {
 	//logic
 	if req.PlayerUUID == "" && req.IsAi {
-		utils.WriteJSONError(w, http.StatusBadRequest, "Player UUID && IsAi is required.")
-		return
+		return "", rejectRequest(http.StatusBadRequest, "Player UUID && IsAi is required.")
+	}
+	//game mode and board geometry
+	if req.Mode == "" {
+		req.Mode = tttService.ModeClassic
+	}
+	if !tttService.IsMode(req.Mode) {
+		return "", rejectRequest(http.StatusBadRequest, "Mode must be one of: "+strings.Join(tttService.Modes, ", ")+".")
+	}
+	if req.Variant == "" {
+		req.Variant = tttService.VariantStandard
+	}
+	if !tttService.IsVariant(req.Variant) {
+		return "", rejectRequest(http.StatusBadRequest, "Variant must be one of: standard, misere, wild, notakto.")
+	}
+	board := tttService.ClassicBoard
+	if req.Rows != 0 || req.Cols != 0 || req.K != 0 {
+		board = tttService.Board{Rows: req.Rows, Cols: req.Cols, K: req.K}
+	}
+	if err := board.Validate(); err != nil {
+		return "", rejectRequest(http.StatusBadRequest, "Invalid board: "+err.Error()+".")
 	}
 	//AI Logic
-	if req.IsAi { //TODO: implement AI logic
-		log.Println("[newGame] Creating new game with AI for player UUID: ", req.PlayerUUID)
+	config := tttStore.GameConfig{IsAi: req.IsAi, Mode: req.Mode, Variant: req.Variant, Rows: board.Rows, Cols: board.Cols, K: board.K,
+		MoveTime: req.MoveTime, BaseTime: req.BaseTime, Increment: req.Increment}
+	if req.MoveHours != 0 {
+		if err := tttService.ValidateMoveHours(req.MoveHours); err != nil {
+			return "", rejectRequest(http.StatusBadRequest, "Invalid time control: "+err.Error()+".")
+		}
+		config.Correspondence = true
+		config.MoveTime, config.BaseTime, config.Increment = req.MoveHours*3600, 0, 0
 	}
-	log.Println("[newGame] Creating new game for player UUID: ", req.PlayerUUID)
-	id, err := tttStore.NewGame()
+	if err := tttService.ValidateTimeControl(config); err != nil {
+		return "", rejectRequest(http.StatusBadRequest, "Invalid time control: "+err.Error()+".")
+	}
+	if err := tttService.ValidateBestOf(req.BestOf); err != nil {
+		return "", rejectRequest(http.StatusBadRequest, "Invalid series: "+err.Error()+".")
+	}
+	config.BestOf = req.BestOf
+	if req.IsAi {
+		botName := req.Bot
+		if botName == "" {
+			botName = req.AILevel
+		}
+		if botName == "" {
+			botName = tttService.DefaultBot
+		}
+		bot, ok := tttService.GetBot(botName)
+		if !ok {
+			return "", rejectRequest(http.StatusBadRequest, "Bot must be one of: "+strings.Join(tttService.BotNames(), ", ")+".")
+		}
+		if !tttService.BotSupports(bot, req.Mode, req.Variant) {
+			return "", rejectRequest(http.StatusBadRequest, "Bot "+botName+" cannot play "+req.Mode+" "+req.Variant+".")
+		}
+		config.Bot = botName
+		log.Println("[createGame] Creating new game with bot ", botName, " for player UUID: ", req.PlayerUUID)
+	}
+	log.Println("[createGame] Creating new game for player UUID: ", req.PlayerUUID)
+	id, err := tttService.NewGame(config)
 	if err != nil {
-		utils.WriteJSONError(w, http.StatusInternalServerError, "Failed to create game.")
-		return
+		return "", rejectRequest(http.StatusInternalServerError, "Failed to create game.")
 	}
-	//write response
-	utils.WriteJSONResponse(w, http.StatusCreated, newGameResp{GameID: id})
-	log.Println("[newGame] Game created successfully with ID: ", id)
+	return id, nil
 }
 
 type getGameStateReq struct {
@@ -56,7 +139,19 @@ type getGameStateReq struct {
 	GameID     string `json:"gameId"`
 }
 type getGameStateResp struct {
-	GameState string `json:"game_state"`
+	GameState     string                  `json:"game_state"`
+	Board         []string                `json:"board"` // rows of cells; in ultimate, the nine sub-boards
+	Turn          string                  `json:"turn,omitempty"`
+	Seats         seats                   `json:"seats"`
+	Status        string                  `json:"status"`        // in_progress, won or draw
+	HistoryLength int                     `json:"historyLength"` // moves played, not counting ones taken back
+	Mode          string                  `json:"mode"`
+	Variant       string                  `json:"variant"`
+	Result        tttService.GameResult   `json:"result"`
+	Clock         *tttService.Clock       `json:"clock,omitempty"`
+	Series        *tttService.SeriesScore `json:"series,omitempty"`
+	NextGame      string                  `json:"nextGameId,omitempty"` // rematch of this game
+	Spectators    int                     `json:"spectators"`
 }
 
 func getGameState(w http.ResponseWriter, r *http.Request) {
@@ -70,19 +165,58 @@ func getGameState(w http.ResponseWriter, r *http.Request) {
 		utils.WriteJSONError(w, http.StatusBadRequest, "Bad Request")
 		return
 	}
-	if req.PlayerUUID == "" || req.GameID == "" {
-		utils.WriteJSONError(w, http.StatusBadRequest, "Player UUID and Game ID Required.")
+	resp, reqErr := gameStateFor(req)
+	if reqErr != nil {
+		utils.WriteJSONError(w, reqErr.Status, reqErr.Message)
 		return
 	}
-	log.Println("[getGameState] Getting game state for player UUID: ", req.PlayerUUID, " and game ID: ", req.GameID)
-	gameState, err := tttStore.GetGameState(req.GameID)
+	utils.WriteJSONResponse(w, http.StatusOK, resp)
+	log.Println("[getGameState] Game state retrieved successfully: ", resp.GameState)
+
+}
+
+// seats holds the player UUID in each seat: "ai" for the computer, empty while open.
+type seats struct {
+	X string `json:"x"`
+	O string `json:"o"`
+}
+
+// gameStateFor loads a game with its board, seats, result, clock and series.
+func gameStateFor(req getGameStateReq) (getGameStateResp, *requestError) {
+	if req.PlayerUUID == "" || req.GameID == "" {
+		return getGameStateResp{}, rejectRequest(http.StatusBadRequest, "Player UUID and Game ID Required.")
+	}
+	log.Println("[gameStateFor] Getting game state for player UUID: ", req.PlayerUUID, " and game ID: ", req.GameID)
+	rows, err := tttStore.GetGameHistory(req.GameID)
 	if err != nil {
-		utils.WriteJSONError(w, http.StatusInternalServerError, "Failed to get game state.")
-		return
+		return getGameStateResp{}, rejectRequest(http.StatusInternalServerError, "Failed to get game state.")
 	}
-	utils.WriteJSONResponse(w, http.StatusOK, getGameStateResp{GameState: gameState.State})
-	log.Println("[getGameState] Game state retrieved successfully: ", gameState)
-
+	if len(rows) == 0 {
+		return getGameStateResp{}, rejectRequest(http.StatusNotFound, "Game not found.")
+	}
+	gameState := rows[len(rows)-1]
+	result := tttService.Result(gameState)
+	var series *tttService.SeriesScore
+	if gameState.Config.BestOf > 1 {
+		if score, err := tttService.Series(req.GameID); err == nil {
+			series = &score
+		}
+	}
+	return getGameStateResp{
+		GameState:     gameState.State,
+		Board:         tttService.BoardRows(gameState),
+		Turn:          result.Turn,
+		Seats:         seats{X: gameState.PlayerX, O: gameState.PlayerO},
+		Status:        result.Status,
+		HistoryLength: len(tttService.History(rows)),
+		Mode:          gameState.Config.Mode,
+		Variant:       gameState.Config.Variant,
+		Result:        result,
+		Clock:         currentClock(req.GameID, gameState),
+		Series:        series,
+		NextGame:      gameState.Config.NextGame,
+		Spectators:    hub.SpectatorCount(req.GameID),
+	}, nil
 }
 
 type choosePlayerReq struct {
@@ -91,7 +225,8 @@ type choosePlayerReq struct {
 	PlayerChoice string `json:"choice"` // "x" or "o"
 }
 type choosePlayerResp struct {
-	GameState string `json:"game_state"`
+	GameState string                `json:"game_state"`
+	Result    tttService.GameResult `json:"result"`
 }
 
 func choosePlayer(w http.ResponseWriter, r *http.Request) {
@@ -105,51 +240,72 @@ func choosePlayer(w http.ResponseWriter, r *http.Request) {
 		utils.WriteJSONError(w, http.StatusBadRequest, "Bad Request")
 		return
 	}
-	if req.PlayerUUID == "" || req.GameID == "" || req.PlayerChoice == "" {
-		utils.WriteJSONError(w, http.StatusBadRequest, "Player UUID and Game ID Required.")
+	gameState, reqErr := chooseSide(req)
+	if reqErr != nil {
+		utils.WriteJSONError(w, reqErr.Status, reqErr.Message)
 		return
 	}
-	log.Println("[choosePlayer] Choosing player for game ID: ", req.GameID)
-	gameState, err := tttStore.GetGameState(req.GameID)
-	if err != nil {
-		utils.WriteJSONError(w, http.StatusInternalServerError, "Failed to get game state.")
-		return
+	utils.WriteJSONResponse(w, http.StatusOK, choosePlayerResp{GameState: gameState.State, Result: tttService.Result(gameState)})
+	log.Println("[choosePlayer] Player chosen successfully: ", gameState)
+}
+
+// chooseSide validates a side choice and seats the player.
+func chooseSide(req choosePlayerReq) (tttStore.GameState, *requestError) {
+	if req.PlayerUUID == "" || req.GameID == "" || req.PlayerChoice == "" {
+		return tttStore.GameState{}, rejectRequest(http.StatusBadRequest, "Player UUID and Game ID Required.")
 	}
+	log.Println("[chooseSide] Choosing player for game ID: ", req.GameID)
 	//correct input
 	if req.PlayerChoice != "x" && req.PlayerChoice != "o" {
-		utils.WriteJSONError(w, http.StatusBadRequest, "Player Choice must be 'x' or 'o'.")
-		return
+		return tttStore.GameState{}, rejectRequest(http.StatusBadRequest, "Player Choice must be 'x' or 'o'.")
 	}
-	//logic to choose player
-	if req.PlayerChoice == "x" && gameState.PlayerX == "" {
-		gameState.PlayerX = req.PlayerUUID
-	} else if gameState.PlayerO == "" {
-		gameState.PlayerO = req.PlayerUUID
-	} else {
-		utils.WriteJSONError(w, http.StatusForbidden, "Players already chosen. Game is in progress.")
-		return
+	gameState, err := seatPlayer(req.GameID, req.PlayerUUID, req.PlayerChoice)
+	if errors.Is(err, tttService.ErrSeatsTaken) {
+		return tttStore.GameState{}, rejectRequest(http.StatusForbidden, "Players already chosen. Game is in progress.")
 	}
-	//update game state
-	tttStore.UpdateGameState(req.GameID, gameState)
-	//get game state truth
-	gameState, err = tttStore.GetGameState(req.GameID)
 	if err != nil {
-		utils.WriteJSONError(w, http.StatusInternalServerError, "Failed to get game state.")
-		return
+		return tttStore.GameState{}, rejectRequest(http.StatusInternalServerError, "Failed to choose player.")
 	}
+	return gameState, nil
+}
 
-	utils.WriteJSONResponse(w, http.StatusOK, choosePlayerResp{GameState: gameState.State})
-	log.Println("[choosePlayer] Player chosen successfully: ", gameState)
+// seatPlayer seats the player, lets the computer open if it now has the
+// move and tells the game group. Returns the state after all of that.
+func seatPlayer(gameID, playerUUID, choice string) (tttStore.GameState, error) {
+	gameState, err := tttService.SeatPlayer(gameID, playerUUID, choice)
+	if err != nil {
+		return gameState, err
+	}
+	return playerSeated(gameID, gameState)
+}
+
+// playerSeated follows up a seat being taken: it updates the lobby, lets
+// the computer open if it now has the move and tells the game group.
+func playerSeated(gameID string, gameState tttStore.GameState) (tttStore.GameState, error) {
+	updateLobby(gameID)
+	//AI opens the game when the human chose o
+	aiGameState, played, err := playAITurn(gameID)
+	if err != nil {
+		return gameState, err
+	}
+	if played {
+		return aiGameState, nil
+	}
+	//tell the game group, which also starts the clock once both seats are filled
+	broadcastGameState(gameID, gameState)
+	return gameState, nil
 }
 
 type makeMoveReq struct {
 	PlayerUUID string `json:"playerId"`
 	GameID     string `json:"gameId"`
-	Move       string `json:"move"` // 2 Character string representing the move char o || x and a number 0-8 (e.g. "x0", "o2", "x8")
+	Move       string `json:"move"` // piece o || x followed by a cell index or zero based row,col (e.g. "x0", "o2", "x112", "x7,7")
 }
 
 type makeMoveResp struct {
-	GameState string `json:"game_state"`
+	GameState string                `json:"game_state"`
+	Result    tttService.GameResult `json:"result"`
+	Clock     *tttService.Clock     `json:"clock,omitempty"`
 }
 
 func makeMove(w http.ResponseWriter, r *http.Request) {
@@ -163,51 +319,216 @@ func makeMove(w http.ResponseWriter, r *http.Request) {
 		utils.WriteJSONError(w, http.StatusBadRequest, "Bad Request")
 		return
 	}
-	if req.PlayerUUID == "" || req.GameID == "" || req.Move == "" || len(req.Move) != 2 {
-		utils.WriteJSONError(w, http.StatusBadRequest, "Player UUID, Game ID, and Move Required. Move must be 2 characters.")
+	finalGameState, reqErr := playMove(req)
+	if reqErr != nil {
+		utils.WriteJSONError(w, reqErr.Status, reqErr.Message)
 		return
 	}
+	utils.WriteJSONResponse(w, http.StatusOK, makeMoveResp{GameState: finalGameState.State, Result: tttService.Result(finalGameState), Clock: currentClock(req.GameID, finalGameState)})
+	log.Println("[makeMove] Move made successfully: ", finalGameState)
+}
+
+// playMove validates and plays a move, tells the game group and lets the
+// computer reply. Returns the state after all of that.
+func playMove(req makeMoveReq) (tttStore.GameState, *requestError) {
+	if req.PlayerUUID == "" || req.GameID == "" || req.Move == "" || len(req.Move) < 2 {
+		return tttStore.GameState{}, rejectRequest(http.StatusBadRequest, "Player UUID, Game ID, and Move Required. Move must be a piece followed by a cell.")
+	}
 	gameState, err := tttStore.GetGameState(req.GameID)
 	if err != nil {
-		utils.WriteJSONError(w, http.StatusInternalServerError, "Failed to get game state.")
-		return
+		return tttStore.GameState{}, rejectRequest(http.StatusInternalServerError, "Failed to get game state.")
 	}
-	if !strings.EqualFold(string(gameState.State[9]), string(req.Move[0])) {
-		log.Printf("[makeMove] Player UUID does not match the current player's turn: %s != %s", string(gameState.State[9]), string(req.Move[0]))
-		utils.WriteJSONError(w, http.StatusForbidden, "It's not your turn.")
-		return
+	//a move after flag fall loses on time
+	if gameState.Status == tttService.StatusActive && tttService.HasTimeControl(gameState.Config) && flagGame(req.GameID) {
+		return tttStore.GameState{}, rejectRequest(http.StatusForbidden, "Time has run out.")
 	}
 	//validate turn
-	turn := gameState.State[9]
-	if turn == '.' {
-		utils.WriteJSONError(w, http.StatusForbidden, "Game is not in progress.")
-		return
+	turn := tttService.Turn(gameState.State)
+	if turn == '.' || gameState.Status != tttService.StatusActive {
+		return tttStore.GameState{}, rejectRequest(http.StatusForbidden, "Game is not in progress.")
 	}
 	var playersTurn string
-	if turn == 'X' {
+	if turn == 'x' {
 		playersTurn = gameState.PlayerX
 	} else {
 		playersTurn = gameState.PlayerO
 	}
+	if isSpectator(req.PlayerUUID, req.GameID) && req.PlayerUUID != gameState.PlayerX && req.PlayerUUID != gameState.PlayerO {
+		return tttStore.GameState{}, rejectRequest(http.StatusForbidden, "Spectators cannot make moves.")
+	}
 	if playersTurn != req.PlayerUUID {
-		log.Printf("[makeMove] Player UUID does not match the current player's turn.", playersTurn, req.PlayerUUID)
-		utils.WriteJSONError(w, http.StatusForbidden, "It's not your turn.")
-		return
+		log.Printf("[playMove] Player UUID does not match the current player's turn: %s != %s", playersTurn, req.PlayerUUID)
+		return tttStore.GameState{}, rejectRequest(http.StatusForbidden, "It's not your turn.")
+	}
+	//validate move against the rules and variant
+	if err := tttService.ValidateMove(gameState, req.Move); err != nil {
+		return tttStore.GameState{}, rejectRequest(http.StatusBadRequest, "Invalid move: "+err.Error()+".")
 	}
 	//begin move logic
-	log.Println("[makeMove] Making move for player UUID: ", req.PlayerUUID, " and game ID: ", req.GameID, " with move: ", req.Move)
-	finalGameState, err := tttService.MakeMove(req.GameID, req.Move)
+	log.Println("[playMove] Making move for player UUID: ", req.PlayerUUID, " and game ID: ", req.GameID, " with move: ", req.Move)
+	finalGameState, err := tttService.MakeMove(req.GameID, req.PlayerUUID, req.Move)
+	switch {
+	case errors.Is(err, tttService.ErrTimeout):
+		//the move found the flag down and ended the game on time
+		if gameState, err := tttStore.GetGameState(req.GameID); err == nil {
+			winner, _ := tttService.ParseStatus(gameState.Status)
+			announceEnd(req.GameID, gameState, tttService.EventTimeout, tttService.Opponent(gameState, winner))
+		}
+		return tttStore.GameState{}, rejectRequest(http.StatusForbidden, "Time has run out.")
+	case errors.Is(err, tttService.ErrGameOver):
+		return tttStore.GameState{}, rejectRequest(http.StatusForbidden, "Game is not in progress.")
+	case errors.Is(err, tttService.ErrNotYourTurn):
+		return tttStore.GameState{}, rejectRequest(http.StatusForbidden, "It's not your turn.")
+	}
 	if err != nil {
-		utils.WriteJSONError(w, http.StatusInternalServerError, "Failed to make move.")
-		return
+		return tttStore.GameState{}, rejectRequest(http.StatusInternalServerError, "Failed to make move.")
 	}
+	//moving instead of answering declines a pending takeback or draw offer
+	clearTakeback(req.GameID)
+	clearDraw(req.GameID)
 	//send game state to websocket
-	gameStateJSON, err := json.Marshal(map[string]string{"game_state": gameState.State})
+	broadcastGameState(req.GameID, finalGameState)
+	//let the computer reply
+	aiGameState, played, err := playAITurn(req.GameID)
+	if err != nil {
+		return tttStore.GameState{}, rejectRequest(http.StatusInternalServerError, "Failed to make AI move.")
+	}
+	if played {
+		finalGameState = aiGameState
+	}
+	return finalGameState, nil
+}
+
+// gameStateMessage is the websocket broadcast sent after every move.
+type gameStateMessage struct {
+	GameState string                `json:"game_state"`
+	Result    tttService.GameResult `json:"result"`
+	Clock     *tttService.Clock     `json:"clock,omitempty"`
+}
+
+// broadcastGameState sends the given state, its result and the clock to
+// every client in the game, and re-arms the clock's timeout.
+func broadcastGameState(gameID string, gameState tttStore.GameState) {
+	clock := watchClock(gameID, gameState)
+	SendStateToGame(gameID, gameStateMessage{GameState: gameState.State, Result: tttService.Result(gameState), Clock: clock})
+}
+
+// playAITurn lets the computer move if it is its turn and broadcasts the result.
+// played is false when the computer had nothing to do.
+func playAITurn(gameID string) (tttStore.GameState, bool, error) {
+	gameState, played, err := tttService.PlayAIMove(gameID)
 	if err != nil {
-		utils.WriteJSONError(w, http.StatusInternalServerError, "Failed to marshal game state.")
+		log.Println("[playAITurn] Failed to make AI move: ", err)
+		return gameState, false, err
+	}
+	if !played {
+		return gameState, false, nil
+	}
+	broadcastGameState(gameID, gameState)
+	return gameState, true, nil
+}
+
+type getHistoryReq struct {
+	PlayerUUID string `json:"playerId"`
+	GameID     string `json:"gameId"`
+}
+type getHistoryResp struct {
+	InitialState string                   `json:"initial_state"`
+	Moves        []tttService.HistoryMove `json:"moves"`
+	Result       tttService.GameResult    `json:"result"`
+}
+
+func getHistory(w http.ResponseWriter, r *http.Request) {
+	log.Println("[getHistory] Request received: ", r.Method, r.URL.Path)
+	if r.Method != http.MethodPost {
+		utils.WriteJSONError(w, http.StatusMethodNotAllowed, "Method not Allowed.")
+		return
+	}
+	var req getHistoryReq
+	if err := utils.ReadRequestBody(w, r, &req); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "Bad Request")
+		return
+	}
+	if req.PlayerUUID == "" || req.GameID == "" {
+		utils.WriteJSONError(w, http.StatusBadRequest, "Player UUID and Game ID Required.")
+		return
+	}
+	rows, err := tttStore.GetGameHistory(req.GameID)
+	if err != nil && !errors.Is(err, tttStore.ErrGameNotFound) {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "Failed to get game history.")
+		return
+	}
+	if len(rows) == 0 {
+		utils.WriteJSONError(w, http.StatusNotFound, "Game not found.")
+		return
+	}
+	moves := tttService.History(rows)
+	if moves == nil {
+		moves = []tttService.HistoryMove{}
+	}
+	utils.WriteJSONResponse(w, http.StatusOK, getHistoryResp{
+		InitialState: rows[0].State,
+		Moves:        moves,
+		Result:       tttService.Result(rows[len(rows)-1]),
+	})
+	log.Println("[getHistory] History retrieved successfully: ", req.GameID, " moves: ", len(moves))
+}
+
+type replayReq struct {
+	PlayerUUID string `json:"playerId"`
+	GameID     string `json:"gameId"`
+	Step       int    `json:"step"` // moves to play from the start; 0 is the empty board, -1 the final position
+}
+type replayResp struct {
+	tttService.ReplayStep
+	TotalSteps int `json:"totalSteps"` // moves in the game; the last step is totalSteps
+}
+
+func replay(w http.ResponseWriter, r *http.Request) {
+	log.Println("[replay] Request received: ", r.Method, r.URL.Path)
+	if r.Method != http.MethodPost {
+		utils.WriteJSONError(w, http.StatusMethodNotAllowed, "Method not Allowed.")
+		return
+	}
+	var req replayReq
+	if err := utils.ReadRequestBody(w, r, &req); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "Bad Request")
+		return
+	}
+	if req.PlayerUUID == "" || req.GameID == "" {
+		utils.WriteJSONError(w, http.StatusBadRequest, "Player UUID and Game ID Required.")
+		return
+	}
+	rows, err := tttStore.GetGameHistory(req.GameID)
+	if err != nil && !errors.Is(err, tttStore.ErrGameNotFound) {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "Failed to get game history.")
+		return
+	}
+	if len(rows) == 0 {
+		utils.WriteJSONError(w, http.StatusNotFound, "Game not found.")
+		return
+	}
+	steps := tttService.Replay(rows)
+	last := len(steps) - 1
+	if req.Step == -1 {
+		req.Step = last
+	}
+	if req.Step < 0 || req.Step > last {
+		utils.WriteJSONError(w, http.StatusBadRequest, "Step must be between 0 and "+strconv.Itoa(last)+".")
+		return
+	}
+	utils.WriteJSONResponse(w, http.StatusOK, replayResp{ReplayStep: steps[req.Step], TotalSteps: last})
+}
+
+type listBotsResp struct {
+	Bots []tttService.BotInfo `json:"bots"`
+}
+
+func listBots(w http.ResponseWriter, r *http.Request) {
+	log.Println("[listBots] Request received: ", r.Method, r.URL.Path)
+	if r.Method != http.MethodGet {
+		utils.WriteJSONError(w, http.StatusMethodNotAllowed, "Method not Allowed.")
 		return
 	}
-	SendToGame(req.GameID, string(gameStateJSON))
-	utils.WriteJSONResponse(w, http.StatusOK, makeMoveResp{GameState: finalGameState})
-	log.Println("[makeMove] Move made successfully: ", gameState)
+	utils.WriteJSONResponse(w, http.StatusOK, listBotsResp{Bots: tttService.ListBots()})
 }
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	tttService "github.com/Maiar0/tictactoe_backend/internal/tictactoe/service"
+	tttStore "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
+)
+
+// AbandonGracePeriod is how long a disconnected player has to reconnect
+// before their active games are forfeited.
+var AbandonGracePeriod = 60 * time.Second
+
+// pendingDraws maps a game ID to the player who offered a draw.
+var (
+	pendingDraws   = make(map[string]string)
+	pendingDrawsMu sync.Mutex
+)
+
+// resign ends the game with the opponent of playerUUID winning.
+func resign(playerUUID, gameID string) *requestError {
+	log.Println("[resign] Player ", playerUUID, " resigning game ", gameID)
+	gameState, err := tttService.Resign(gameID, playerUUID)
+	if err != nil {
+		return rejectRequest(http.StatusForbidden, "Resign failed: "+err.Error()+".")
+	}
+	announceEnd(gameID, gameState, tttService.EventResigned, playerUUID)
+	return nil
+}
+
+// offerDraw offers a draw to the opponent. The computer always declines.
+func offerDraw(playerUUID, gameID string) *requestError {
+	log.Println("[offerDraw] Player ", playerUUID, " offering a draw in game ", gameID)
+	gameState, reqErr := currentGame(gameID)
+	if reqErr != nil {
+		return reqErr
+	}
+	if gameState.Status != tttService.StatusActive {
+		return rejectRequest(http.StatusForbidden, "Game is not in progress.")
+	}
+	opponent := tttService.Opponent(gameState, playerUUID)
+	if opponent == "" {
+		return rejectRequest(http.StatusForbidden, "You are not playing in this game.")
+	}
+	if opponent == tttService.AIPlayerID {
+		SendToPlayer(playerUUID, gameEvent{Event: "draw_declined", GameID: gameID, PlayerID: playerUUID})
+		return nil
+	}
+	pendingDrawsMu.Lock()
+	pendingDraws[gameID] = playerUUID
+	pendingDrawsMu.Unlock()
+	SendToGame(gameID, gameEvent{Event: "draw_offered", GameID: gameID, PlayerID: playerUUID})
+	return nil
+}
+
+// answerDraw accepts or declines the pending draw offer on behalf of the opponent.
+func answerDraw(playerUUID, gameID string, accept bool) *requestError {
+	log.Println("[answerDraw] Player ", playerUUID, " answering draw offer in game ", gameID, ": ", accept)
+	gameState, reqErr := currentGame(gameID)
+	if reqErr != nil {
+		return reqErr
+	}
+	pendingDrawsMu.Lock()
+	offeredBy, ok := pendingDraws[gameID]
+	if ok && tttService.Opponent(gameState, offeredBy) == playerUUID {
+		delete(pendingDraws, gameID)
+	} else {
+		ok = false
+	}
+	pendingDrawsMu.Unlock()
+	if !ok {
+		return rejectRequest(http.StatusForbidden, "No draw offer to answer.")
+	}
+	if !accept {
+		SendToGame(gameID, gameEvent{Event: "draw_declined", GameID: gameID, PlayerID: offeredBy})
+		return nil
+	}
+	gameState, err := tttService.AgreeDraw(gameID)
+	if err != nil {
+		return rejectRequest(http.StatusForbidden, "Draw failed: "+err.Error()+".")
+	}
+	announceEnd(gameID, gameState, tttService.EventDrawAgreed, offeredBy)
+	return nil
+}
+
+// clearDraw drops any pending draw offer once the game moves on.
+func clearDraw(gameID string) {
+	pendingDrawsMu.Lock()
+	delete(pendingDraws, gameID)
+	pendingDrawsMu.Unlock()
+}
+
+// scheduleAbandonment forfeits playerUUID's active games in gameIDs unless
+// they reconnect within AbandonGracePeriod.
+func scheduleAbandonment(playerUUID string, gameIDs []string) {
+	if len(gameIDs) == 0 {
+		return
+	}
+	log.Println("[scheduleAbandonment] Player ", playerUUID, " has ", AbandonGracePeriod, " to reconnect to ", gameIDs)
+	time.AfterFunc(AbandonGracePeriod, func() {
+		if hub.Connected(playerUUID) {
+			log.Println("[scheduleAbandonment] Player ", playerUUID, " reconnected in time")
+			return
+		}
+		for _, gameID := range gameIDs {
+			gameState, err := tttService.Abandon(gameID, playerUUID)
+			if err != nil {
+				log.Println("[scheduleAbandonment] Not forfeiting game ", gameID, ": ", err)
+				continue
+			}
+			announceEnd(gameID, gameState, tttService.EventAbandoned, playerUUID)
+		}
+	})
+}
+
+// announceEnd tells the game group how the game ended, then sends the final state.
+func announceEnd(gameID string, gameState tttStore.GameState, event, playerUUID string) {
+	clearTakeback(gameID)
+	clearDraw(gameID)
+	SendToGame(gameID, gameEvent{Event: event, GameID: gameID, PlayerID: playerUUID})
+	broadcastGameState(gameID, gameState)
+}
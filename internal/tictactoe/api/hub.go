@@ -0,0 +1,510 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// sendBufferSize is how many outgoing messages a connection may have
+	// queued before it is treated as a slow consumer and disconnected.
+	sendBufferSize = 64
+	// writeWait bounds a single write so a stalled peer can't hold its pump forever.
+	writeWait = 10 * time.Second
+	// pongWait is how long a connection may stay silent, not even answering
+	// a ping, before it is treated as dead.
+	pongWait = 60 * time.Second
+	// pingPeriod is how often the write pump pings; it must be under pongWait.
+	pingPeriod = pongWait * 9 / 10
+	// replayBufferSize is how many recent broadcasts of each game are kept
+	// for clients that resume after a dropped connection.
+	replayBufferSize = 128
+	// maxReplayGames bounds how many games keep a replay buffer; the game
+	// that broadcast least recently loses its buffer first.
+	maxReplayGames = 1024
+)
+
+// client is one websocket connection. Only its write pump writes to conn and
+// only the hub sends on or closes send.
+type client struct {
+	conn     *websocket.Conn
+	protocol string // negotiated subprotocol, "" for the original format
+	send     chan []byte
+
+	// owned by the hub goroutine
+	ids    map[string]bool // player UUIDs registered on this connection
+	closed bool
+}
+
+func newClient(conn *websocket.Conn) *client {
+	return &client{conn: conn, protocol: conn.Subprotocol(), send: make(chan []byte, sendBufferSize), ids: make(map[string]bool)}
+}
+
+// writePump writes queued messages to the connection, and pings it every
+// pingPeriod, until the hub closes send.
+func (c *client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+	defer c.conn.Close()
+	for {
+		select {
+		case message, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				c.writeFailed(err)
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.writeFailed(err)
+				return
+			}
+		}
+	}
+}
+
+// writeFailed closes the connection, which ends its read loop and so
+// unregisters it, then drains send so the hub never blocks on it until then.
+func (c *client) writeFailed(err error) {
+	log.Printf("[writePump] Write failed: %v", err)
+	c.conn.Close()
+	for range c.send {
+	}
+}
+
+// departure is a player dropped from the hub and the rooms they were in.
+type departure struct {
+	playerUUID string
+	games      []string // games they were registered to play in
+	spectated  []string // games they were watching
+}
+
+// registration binds a player UUID to a connection.
+type registration struct {
+	client     *client
+	playerUUID string
+	done       chan struct{}
+}
+
+// unregistration drops every player on a closed connection.
+type unregistration struct {
+	client *client
+	left   chan []departure
+}
+
+// delivery is a message for one connection, one player, a game's players and
+// spectators, or the lobby.
+type delivery struct {
+	client     *client
+	playerUUID string
+	gameID     string
+	lobby      bool
+	out        outgoing
+	done       chan struct{}
+}
+
+// Hub owns every connection and room. All of its state is touched only by
+// the run goroutine; other goroutines talk to it through channels and wait
+// for each request to be handled, so a caller's requests apply in order.
+type Hub struct {
+	register   chan registration
+	unregister chan unregistration
+	broadcast  chan delivery
+	rooms      chan func() // membership changes and queries
+
+	clients    map[string]*client         // player UUID -> connection
+	players    map[string]map[string]bool // game -> players
+	spectators map[string]map[string]bool // game -> spectators
+	lobby      map[string]bool            // lobby subscribers
+	epoch      string                     // names this process's seqs; set once, safe to read anywhere
+	seqs       map[string]int64           // game -> seq of its last broadcast
+	replays    map[string]*replayBuffer   // game -> its most recent broadcasts
+	broadcasts int64                      // broadcasts so far, to age replay buffers
+}
+
+// replayBuffer holds a game's last replayBufferSize broadcasts, oldest first.
+type replayBuffer struct {
+	entries  []sequenced
+	lastUsed int64
+}
+
+type sequenced struct {
+	seq    int64
+	frames frames
+}
+
+func newHub() *Hub {
+	return &Hub{
+		epoch:      newEpoch(),
+		register:   make(chan registration),
+		unregister: make(chan unregistration),
+		broadcast:  make(chan delivery),
+		rooms:      make(chan func()),
+		clients:    make(map[string]*client),
+		players:    make(map[string]map[string]bool),
+		spectators: make(map[string]map[string]bool),
+		lobby:      make(map[string]bool),
+		seqs:       make(map[string]int64),
+		replays:    make(map[string]*replayBuffer),
+	}
+}
+
+var hub = newHub()
+
+// newEpoch returns a random ID for this run of the server. Seqs restart
+// from 1 with every run, so a seq only means something alongside its epoch.
+func newEpoch() string {
+	b := make([]byte, 8)
+	rand.Read(b) //never fails since Go 1.24
+	return hex.EncodeToString(b)
+}
+
+func init() {
+	go hub.run()
+}
+
+func (h *Hub) run() {
+	for {
+		select {
+		case r := <-h.register:
+			h.bind(r.client, r.playerUUID)
+			close(r.done)
+		case u := <-h.unregister:
+			var left []departure
+			for playerUUID := range u.client.ids {
+				left = append(left, h.drop(playerUUID))
+			}
+			h.close(u.client)
+			u.left <- left
+		case d := <-h.broadcast:
+			h.deliver(d)
+			close(d.done)
+		case op := <-h.rooms:
+			op()
+		}
+	}
+}
+
+// bind points playerUUID at c, taking it off any earlier connection.
+func (h *Hub) bind(c *client, playerUUID string) {
+	if old, exists := h.clients[playerUUID]; exists && old != c {
+		delete(old.ids, playerUUID)
+	}
+	h.clients[playerUUID] = c
+	c.ids[playerUUID] = true
+}
+
+// join puts playerUUID in a game's players, taking them out of its
+// spectators so they are not sent every broadcast twice.
+func (h *Hub) join(playerUUID, gameID string) {
+	delete(h.spectators[gameID], playerUUID)
+	if h.players[gameID] == nil {
+		h.players[gameID] = make(map[string]bool)
+	}
+	h.players[gameID][playerUUID] = true
+}
+
+// deliver queues the message on every connection it is addressed to, in
+// the encoding of each connection's protocol.
+func (h *Hub) deliver(d delivery) {
+	gameBroadcast := d.client == nil && !d.lobby && d.gameID != ""
+	if gameBroadcast {
+		h.seqs[d.gameID]++
+		d.out.envelope.Seq = h.seqs[d.gameID]
+		d.out.envelope.Epoch = h.epoch
+	}
+	v1, err := json.Marshal(d.out.envelope)
+	if err != nil {
+		log.Printf("[Hub] Failed to marshal envelope: %v", err)
+		return
+	}
+	frames := frames{legacy: d.out.legacy, v1: v1}
+	if gameBroadcast {
+		h.remember(d.gameID, sequenced{seq: d.out.envelope.Seq, frames: frames})
+	}
+	switch {
+	case d.client != nil:
+		h.enqueue(d.client, frames)
+	case d.lobby:
+		for playerUUID := range h.lobby {
+			h.enqueue(h.clients[playerUUID], frames)
+		}
+	case gameBroadcast:
+		for playerUUID := range h.players[d.gameID] {
+			h.enqueue(h.clients[playerUUID], frames)
+		}
+		for playerUUID := range h.spectators[d.gameID] {
+			h.enqueue(h.clients[playerUUID], frames)
+		}
+	default:
+		h.enqueue(h.clients[d.playerUUID], frames)
+	}
+}
+
+// frames is one message encoded for each protocol version.
+type frames struct {
+	legacy []byte
+	v1     []byte
+}
+
+// enqueue never blocks: a connection whose buffer is full is evicted. Its
+// write pump then closes the socket and the read loop unregisters it.
+func (h *Hub) enqueue(c *client, frames frames) {
+	if c == nil || c.closed {
+		return
+	}
+	data := frames.legacy
+	if c.protocol == ProtocolV1 {
+		data = frames.v1
+	}
+	if data == nil {
+		return
+	}
+	select {
+	case c.send <- data:
+	default:
+		log.Printf("[Hub] Evicting slow connection for players %v", c.ids)
+		h.close(c)
+	}
+}
+
+// remember keeps a broadcast for replay, dropping the oldest of the game's
+// buffer and, past maxReplayGames, the stalest game's buffer.
+func (h *Hub) remember(gameID string, entry sequenced) {
+	h.broadcasts++
+	buffer, exists := h.replays[gameID]
+	if !exists {
+		if len(h.replays) >= maxReplayGames {
+			stalest := ""
+			for id, other := range h.replays {
+				if stalest == "" || other.lastUsed < h.replays[stalest].lastUsed {
+					stalest = id
+				}
+			}
+			delete(h.replays, stalest)
+		}
+		buffer = &replayBuffer{}
+		h.replays[gameID] = buffer
+	}
+	buffer.lastUsed = h.broadcasts
+	buffer.entries = append(buffer.entries, entry)
+	if len(buffer.entries) > replayBufferSize {
+		buffer.entries = buffer.entries[len(buffer.entries)-replayBufferSize:]
+	}
+}
+
+// covers reports whether every broadcast of a game after seq is still buffered.
+func (h *Hub) covers(gameID string, seq int64) bool {
+	current := h.seqs[gameID]
+	if seq < 0 || seq > current {
+		return false
+	}
+	if seq == current {
+		return true
+	}
+	buffer := h.replays[gameID]
+	return buffer != nil && len(buffer.entries) > 0 && buffer.entries[0].seq <= seq+1
+}
+
+// replay queues a game's buffered broadcasts after seq on c and returns how many.
+func (h *Hub) replay(c *client, gameID string, seq int64) int {
+	replayed := 0
+	if buffer := h.replays[gameID]; buffer != nil {
+		for _, entry := range buffer.entries {
+			if entry.seq > seq {
+				h.enqueue(c, entry.frames)
+				replayed++
+			}
+		}
+	}
+	return replayed
+}
+
+func (h *Hub) close(c *client) {
+	if !c.closed {
+		c.closed = true
+		close(c.send)
+	}
+}
+
+// drop removes a player's connection and every room they are in.
+func (h *Hub) drop(playerUUID string) departure {
+	left := departure{playerUUID: playerUUID}
+	if c, exists := h.clients[playerUUID]; exists {
+		delete(c.ids, playerUUID)
+		delete(h.clients, playerUUID)
+	}
+	delete(h.lobby, playerUUID)
+	for gameID, players := range h.players {
+		if players[playerUUID] {
+			delete(players, playerUUID)
+			left.games = append(left.games, gameID)
+		}
+	}
+	for gameID, spectators := range h.spectators {
+		if spectators[playerUUID] {
+			delete(spectators, playerUUID)
+			left.spectated = append(left.spectated, gameID)
+		}
+	}
+	return left
+}
+
+// do runs op on the hub goroutine and waits for it.
+func (h *Hub) do(op func()) {
+	done := make(chan struct{})
+	h.rooms <- func() {
+		op()
+		close(done)
+	}
+	<-done
+}
+
+// Register binds playerUUID to c, replacing any earlier connection for that player.
+func (h *Hub) Register(c *client, playerUUID string) {
+	done := make(chan struct{})
+	h.register <- registration{client: c, playerUUID: playerUUID, done: done}
+	<-done
+}
+
+// Unregister drops a closed connection and reports the players it carried.
+func (h *Hub) Unregister(c *client) []departure {
+	left := make(chan []departure, 1)
+	h.unregister <- unregistration{client: c, left: left}
+	return <-left
+}
+
+// Leave removes a player from the hub as if their connection had closed,
+// without closing it.
+func (h *Hub) Leave(playerUUID string) departure {
+	var left departure
+	h.do(func() { left = h.drop(playerUUID) })
+	return left
+}
+
+// Join adds a player to a game's room.
+func (h *Hub) Join(playerUUID, gameID string) {
+	h.do(func() { h.join(playerUUID, gameID) })
+}
+
+// Spectate adds a spectator to a game's room. added is false if they were already watching.
+func (h *Hub) Spectate(playerUUID, gameID string) (added bool) {
+	h.do(func() {
+		if h.spectators[gameID] == nil {
+			h.spectators[gameID] = make(map[string]bool)
+		}
+		added = !h.spectators[gameID][playerUUID]
+		h.spectators[gameID][playerUUID] = true
+	})
+	return added
+}
+
+// SubscribeLobby adds or removes a player from lobby updates.
+func (h *Hub) SubscribeLobby(playerUUID string, subscribe bool) {
+	h.do(func() {
+		if subscribe {
+			h.lobby[playerUUID] = true
+		} else {
+			delete(h.lobby, playerUUID)
+		}
+	})
+}
+
+// Connected reports whether a player has a live connection.
+func (h *Hub) Connected(playerUUID string) (connected bool) {
+	h.do(func() { _, connected = h.clients[playerUUID] })
+	return connected
+}
+
+// Bound reports whether playerUUID is registered on c.
+func (h *Hub) Bound(c *client, playerUUID string) (bound bool) {
+	h.do(func() { bound = h.clients[playerUUID] == c })
+	return bound
+}
+
+// Players lists the players in a game's room.
+func (h *Hub) Players(gameID string) (players []string) {
+	h.do(func() {
+		for playerUUID := range h.players[gameID] {
+			players = append(players, playerUUID)
+		}
+	})
+	return players
+}
+
+// GamesOf lists the games whose rooms a player is in.
+func (h *Hub) GamesOf(playerUUID string) (games []string) {
+	h.do(func() {
+		for gameID, players := range h.players {
+			if players[playerUUID] {
+				games = append(games, gameID)
+			}
+		}
+	})
+	return games
+}
+
+// IsSpectator reports whether a player is watching a game.
+func (h *Hub) IsSpectator(playerUUID, gameID string) (watching bool) {
+	h.do(func() { watching = h.spectators[gameID][playerUUID] })
+	return watching
+}
+
+// SpectatorCount reports how many spectators are watching a game.
+func (h *Hub) SpectatorCount(gameID string) (count int) {
+	h.do(func() { count = len(h.spectators[gameID]) })
+	return count
+}
+
+// Seq returns the seq of a game's last broadcast.
+func (h *Hub) Seq(gameID string) (seq int64) {
+	h.do(func() { seq = h.seqs[gameID] })
+	return seq
+}
+
+// Resume binds playerUUID to c, joins them to the game's room and replays
+// the broadcasts they missed after lastSeq, all before any new broadcast can
+// reach them. If lastSeq is from another epoch or the buffer no longer
+// reaches back to it, resync, the game as loaded at resyncSeq, is queued
+// instead, followed by the buffered broadcasts after resyncSeq. Returns how
+// many broadcasts were replayed, whether resync was sent and the game's
+// current seq.
+func (h *Hub) Resume(c *client, playerUUID, gameID, epoch string, lastSeq int64, resync outgoing, resyncSeq int64) (replayed int, resynced bool, seq int64) {
+	h.do(func() {
+		h.bind(c, playerUUID)
+		h.join(playerUUID, gameID)
+		seq = h.seqs[gameID]
+		if epoch == h.epoch && h.covers(gameID, lastSeq) {
+			replayed = h.replay(c, gameID, lastSeq)
+			return
+		}
+		resync.envelope.Seq = resyncSeq
+		resync.envelope.Epoch = h.epoch
+		v1, err := json.Marshal(resync.envelope)
+		if err != nil {
+			log.Printf("[Hub] Failed to marshal envelope: %v", err)
+			return
+		}
+		h.enqueue(c, frames{legacy: resync.legacy, v1: v1})
+		resynced = true
+		replayed = h.replay(c, gameID, resyncSeq)
+	})
+	return replayed, resynced, seq
+}
+
+// Send queues data for the addressed connections and returns once it is queued.
+func (h *Hub) Send(d delivery) {
+	d.done = make(chan struct{})
+	h.broadcast <- d
+	<-d.done
+}
@@ -0,0 +1,280 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// startHub runs a hub of its own for one test.
+func startHub() *Hub {
+	h := newHub()
+	go h.run()
+	return h
+}
+
+// testClient is a connection with no socket behind it; tests read what the
+// hub queues for it straight from send.
+func testClient() *client {
+	return &client{protocol: ProtocolV1, send: make(chan []byte, sendBufferSize), ids: make(map[string]bool)}
+}
+
+// broadcast is a game event for everyone in gameID.
+func broadcast(t *testing.T, gameID, event string) delivery {
+	out, err := encode(gameEvent{Event: event, GameID: gameID})
+	if err != nil {
+		t.Fatalf("encode %s: %v", event, err)
+	}
+	return delivery{gameID: gameID, out: out}
+}
+
+// queued returns the envelopes waiting on c without blocking, and whether
+// the hub has closed c.
+func queued(t *testing.T, c *client) (envelopes []Envelope, closed bool) {
+	for {
+		select {
+		case data, ok := <-c.send:
+			if !ok {
+				return envelopes, true
+			}
+			var envelope Envelope
+			if err := json.Unmarshal(data, &envelope); err != nil {
+				t.Fatalf("bad frame %s: %v", data, err)
+			}
+			envelopes = append(envelopes, envelope)
+		default:
+			return envelopes, false
+		}
+	}
+}
+
+func TestHubConcurrentRegisterAndBroadcast(t *testing.T) {
+	h := startHub()
+	const players, broadcasts = 20, 30
+	clients := make([]*client, players)
+	var wg sync.WaitGroup
+	for i := range clients {
+		clients[i] = testClient()
+		wg.Add(1)
+		go func(c *client, playerUUID string) {
+			defer wg.Done()
+			h.Register(c, playerUUID)
+			h.Join(playerUUID, "g")
+		}(clients[i], fmt.Sprint("p", i))
+	}
+	during := broadcast(t, "g", "during")
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < broadcasts; i++ {
+			h.Send(during)
+		}
+	}()
+	wg.Wait()
+	h.Send(broadcast(t, "g", "after"))
+
+	for i, c := range clients {
+		envelopes, closed := queued(t, c)
+		if closed {
+			t.Fatalf("p%d: connection closed", i)
+		}
+		if len(envelopes) == 0 || envelopes[len(envelopes)-1].Type != "after" {
+			t.Errorf("p%d: did not get the broadcast sent after everyone joined", i)
+		}
+		for j := 1; j < len(envelopes); j++ {
+			if envelopes[j].Seq != envelopes[j-1].Seq+1 {
+				t.Errorf("p%d: seq %d followed by %d", i, envelopes[j-1].Seq, envelopes[j].Seq)
+			}
+		}
+	}
+	if got := h.Seq("g"); got != broadcasts+1 {
+		t.Errorf("got seq %d, want %d", got, broadcasts+1)
+	}
+}
+
+func TestHubEvictsFullConnection(t *testing.T) {
+	h := startHub()
+	slow, fast := testClient(), testClient()
+	h.Register(slow, "slow")
+	h.Register(fast, "fast")
+	h.Join("slow", "g")
+	h.Join("fast", "g")
+	tick := broadcast(t, "g", "tick")
+	for i := 0; i < sendBufferSize+1; i++ {
+		h.Send(tick)
+		if i < sendBufferSize {
+			queued(t, fast)
+		}
+	}
+	envelopes, closed := queued(t, slow)
+	if !closed {
+		t.Errorf("slow connection was not evicted")
+	}
+	if len(envelopes) != sendBufferSize {
+		t.Errorf("slow connection got %d frames before eviction, want %d", len(envelopes), sendBufferSize)
+	}
+	if envelopes, closed := queued(t, fast); closed || len(envelopes) != 1 {
+		t.Errorf("fast connection: got %d frames, closed %v; want 1 frame, open", len(envelopes), closed)
+	}
+	//later broadcasts skip the evicted connection instead of panicking
+	h.Send(tick)
+}
+
+func TestHubUnregisterDuringBroadcast(t *testing.T) {
+	h := startHub()
+	const players = 20
+	clients := make([]*client, players)
+	var drained sync.WaitGroup
+	for i := range clients {
+		c := testClient()
+		clients[i] = c
+		h.Register(c, fmt.Sprint("p", i))
+		h.Join(fmt.Sprint("p", i), "g")
+		drained.Add(1)
+		go func() {
+			defer drained.Done()
+			for range c.send {
+			}
+		}()
+	}
+	tick := broadcast(t, "g", "tick")
+	stop := make(chan struct{})
+	broadcasting := make(chan struct{})
+	go func() {
+		defer close(broadcasting)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				h.Send(tick)
+			}
+		}
+	}()
+	var wg sync.WaitGroup
+	for i, c := range clients {
+		wg.Add(1)
+		go func(c *client, playerUUID string) {
+			defer wg.Done()
+			left := h.Unregister(c)
+			if len(left) != 1 || left[0].playerUUID != playerUUID || len(left[0].games) != 1 {
+				t.Errorf("%s: got departures %+v", playerUUID, left)
+			}
+		}(c, fmt.Sprint("p", i))
+	}
+	wg.Wait()
+	close(stop)
+	<-broadcasting
+	//every send channel is closed once its connection is unregistered
+	drained.Wait()
+	if players := h.Players("g"); len(players) != 0 {
+		t.Errorf("players left in the room: %v", players)
+	}
+}
+
+func TestHubSeqPerGame(t *testing.T) {
+	h := startHub()
+	c := testClient()
+	h.Register(c, "p")
+	h.Join("p", "a")
+	h.Join("p", "b")
+	for _, gameID := range []string{"a", "b", "a", "a", "b"} {
+		h.Send(broadcast(t, gameID, "tick"))
+	}
+	reply, err := encode(serverMessage{Type: "heartbeat", Legacy: "heartbeat"})
+	if err != nil {
+		t.Fatalf("encode heartbeat: %v", err)
+	}
+	h.Send(delivery{client: c, out: reply})
+
+	envelopes, _ := queued(t, c)
+	var got []string
+	for _, envelope := range envelopes {
+		if envelope.Type == "heartbeat" {
+			//replies to one connection are not numbered
+			got = append(got, fmt.Sprint(envelope.Type, envelope.Seq, envelope.Epoch))
+			continue
+		}
+		if envelope.Epoch != h.epoch {
+			t.Errorf("seq %d has epoch %q, want %q", envelope.Seq, envelope.Epoch, h.epoch)
+		}
+		var event gameEvent
+		if err := json.Unmarshal(envelope.Payload, &event); err != nil {
+			t.Fatalf("bad payload %s: %v", envelope.Payload, err)
+		}
+		got = append(got, fmt.Sprint(event.GameID, envelope.Seq))
+	}
+	want := fmt.Sprint([]string{"a1", "b1", "a2", "a3", "b2", "heartbeat0"})
+	if fmt.Sprint(got) != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if h.Seq("a") != 3 || h.Seq("b") != 2 {
+		t.Errorf("got seqs a=%d b=%d, want 3 and 2", h.Seq("a"), h.Seq("b"))
+	}
+}
+
+func TestHubResume(t *testing.T) {
+	tests := []struct {
+		name       string
+		broadcasts int
+		ownEpoch   bool   // resume from this hub's epoch
+		epoch      string // otherwise
+		lastSeq    int64
+		resyncSeq  int64
+		replayed   int
+		resynced   bool
+		seqs       []int64 // seqs of the frames queued, the resync's first if sent
+	}{
+		{name: "replays from the buffer", broadcasts: 5, ownEpoch: true, lastSeq: 2, resyncSeq: 5, replayed: 3, seqs: []int64{3, 4, 5}},
+		{name: "up to date", broadcasts: 5, ownEpoch: true, lastSeq: 5, resyncSeq: 5},
+		{name: "nothing broadcast yet", ownEpoch: true},
+		{name: "another epoch", broadcasts: 5, epoch: "previous", lastSeq: 2, resyncSeq: 5, resynced: true, seqs: []int64{5}},
+		{name: "no epoch", broadcasts: 5, lastSeq: 0, resyncSeq: 5, resynced: true, seqs: []int64{5}},
+		{name: "seq too old", broadcasts: replayBufferSize + 10, ownEpoch: true, lastSeq: 5, resyncSeq: replayBufferSize + 10, resynced: true, seqs: []int64{replayBufferSize + 10}},
+		{name: "seq ahead", broadcasts: 5, ownEpoch: true, lastSeq: 9, resyncSeq: 5, resynced: true, seqs: []int64{5}},
+		{name: "broadcasts after the resync loaded", broadcasts: 5, epoch: "previous", lastSeq: 4, resyncSeq: 3, replayed: 2, resynced: true, seqs: []int64{3, 4, 5}},
+	}
+	for _, tt := range tests {
+		h := startHub()
+		for i := 0; i < tt.broadcasts; i++ {
+			h.Send(broadcast(t, "g", "tick"))
+		}
+		resync, err := encode(serverMessage{Type: "resync", Payload: "state", Legacy: "state"})
+		if err != nil {
+			t.Fatalf("encode resync: %v", err)
+		}
+		epoch := tt.epoch
+		if tt.ownEpoch {
+			epoch = h.epoch
+		}
+		c := testClient()
+		replayed, resynced, seq := h.Resume(c, "p", "g", epoch, tt.lastSeq, resync, tt.resyncSeq)
+		if replayed != tt.replayed || resynced != tt.resynced || seq != int64(tt.broadcasts) {
+			t.Errorf("%s: got replayed %d, resynced %v, seq %d; want %d, %v, %d", tt.name, replayed, resynced, seq, tt.replayed, tt.resynced, tt.broadcasts)
+		}
+		envelopes, _ := queued(t, c)
+		var seqs []int64
+		for i, envelope := range envelopes {
+			wantType := "tick"
+			if tt.resynced && i == 0 {
+				wantType = "resync"
+			}
+			if envelope.Type != wantType {
+				t.Errorf("%s: frame %d is %q, want %q", tt.name, i, envelope.Type, wantType)
+			}
+			if envelope.Epoch != h.epoch {
+				t.Errorf("%s: frame %d has epoch %q, want %q", tt.name, i, envelope.Epoch, h.epoch)
+			}
+			seqs = append(seqs, envelope.Seq)
+		}
+		if fmt.Sprint(seqs) != fmt.Sprint(tt.seqs) {
+			t.Errorf("%s: got seqs %v, want %v", tt.name, seqs, tt.seqs)
+		}
+		//the player is in the room, so the next broadcast follows on
+		h.Send(broadcast(t, "g", "tick"))
+		if envelopes, _ := queued(t, c); len(envelopes) != 1 || envelopes[0].Seq != int64(tt.broadcasts)+1 {
+			t.Errorf("%s: next broadcast not delivered after resume: %+v", tt.name, envelopes)
+		}
+	}
+}
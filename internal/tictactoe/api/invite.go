@@ -0,0 +1,139 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	tttService "github.com/Maiar0/tictactoe_backend/internal/tictactoe/service"
+	utils "github.com/Maiar0/tictactoe_backend/internal/utils"
+)
+
+type createInviteReq struct {
+	PlayerUUID string `json:"playerId"`
+	GameID     string `json:"gameId"`
+	ExpiresIn  int    `json:"expiresIn"` // seconds; defaults to a day
+}
+type createInviteResp struct {
+	Code      string `json:"code"`      // short code to read out or type
+	Link      string `json:"link"`      // one-time join link: POST it with the joining player's playerId
+	ExpiresAt int64  `json:"expiresAt"` // unix seconds
+}
+
+func createInvite(w http.ResponseWriter, r *http.Request) {
+	log.Println("[createInvite] Request received: ", r.Method, r.URL.Path)
+	if r.Method != http.MethodPost {
+		utils.WriteJSONError(w, http.StatusMethodNotAllowed, "Method not Allowed.")
+		return
+	}
+	var req createInviteReq
+	if err := utils.ReadRequestBody(w, r, &req); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "Bad Request")
+		return
+	}
+	if req.PlayerUUID == "" || req.GameID == "" {
+		utils.WriteJSONError(w, http.StatusBadRequest, "Player UUID and Game ID Required.")
+		return
+	}
+	invite, err := tttService.CreateInvite(req.GameID, req.PlayerUUID, time.Duration(req.ExpiresIn)*time.Second)
+	if errors.Is(err, tttService.ErrSeatsTaken) {
+		utils.WriteJSONError(w, http.StatusForbidden, "Players already chosen. Game is in progress.")
+		return
+	}
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "Failed to create invite: "+err.Error()+".")
+		return
+	}
+	utils.WriteJSONResponse(w, http.StatusCreated, createInviteResp{Code: invite.Code, Link: acceptInvitePath + "?code=" + invite.Token, ExpiresAt: invite.ExpiresAt})
+	log.Println("[createInvite] Invite created: ", invite.Code, " for game ", invite.GameID)
+}
+
+// acceptInvitePath is the accept endpoint that invite links point at.
+const acceptInvitePath = "/api/v1/tictactoe/invite/accept"
+
+type acceptInviteReq struct {
+	PlayerUUID string `json:"playerId"`
+	Code       string `json:"code"` // short code or the token from a link; a link carries it in the query string
+}
+type acceptInviteResp struct {
+	GameID    string                `json:"gameId"`
+	GameState string                `json:"game_state"`
+	Result    tttService.GameResult `json:"result"`
+}
+
+func acceptInvite(w http.ResponseWriter, r *http.Request) {
+	log.Println("[acceptInvite] Request received: ", r.Method, r.URL.Path)
+	if r.Method != http.MethodPost {
+		utils.WriteJSONError(w, http.StatusMethodNotAllowed, "Method not Allowed.")
+		return
+	}
+	var req acceptInviteReq
+	if err := utils.ReadRequestBody(w, r, &req); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "Bad Request")
+		return
+	}
+	if req.Code == "" {
+		req.Code = r.URL.Query().Get("code")
+	}
+	if req.PlayerUUID == "" || req.Code == "" {
+		utils.WriteJSONError(w, http.StatusBadRequest, "Player UUID and Code Required.")
+		return
+	}
+	invite, gameState, err := tttService.RedeemInvite(req.Code, req.PlayerUUID)
+	if errors.Is(err, tttService.ErrSeatsTaken) {
+		utils.WriteJSONError(w, http.StatusForbidden, "Players already chosen. Game is in progress.")
+		return
+	}
+	if err != nil {
+		writeInviteError(w, err)
+		return
+	}
+	gameState, err = playerSeated(invite.GameID, gameState)
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "Failed to make AI move.")
+		return
+	}
+	utils.WriteJSONResponse(w, http.StatusOK, acceptInviteResp{GameID: invite.GameID, GameState: gameState.State, Result: tttService.Result(gameState)})
+	log.Println("[acceptInvite] Player ", req.PlayerUUID, " joined game ", invite.GameID, " by invite")
+}
+
+type revokeInviteReq struct {
+	PlayerUUID string `json:"playerId"`
+	Code       string `json:"code"` // short code or the token from a link
+}
+
+func revokeInvite(w http.ResponseWriter, r *http.Request) {
+	log.Println("[revokeInvite] Request received: ", r.Method, r.URL.Path)
+	if r.Method != http.MethodPost {
+		utils.WriteJSONError(w, http.StatusMethodNotAllowed, "Method not Allowed.")
+		return
+	}
+	var req revokeInviteReq
+	if err := utils.ReadRequestBody(w, r, &req); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "Bad Request")
+		return
+	}
+	if req.PlayerUUID == "" || req.Code == "" {
+		utils.WriteJSONError(w, http.StatusBadRequest, "Player UUID and Code Required.")
+		return
+	}
+	if err := tttService.RevokeInvite(req.Code, req.PlayerUUID); err != nil {
+		writeInviteError(w, err)
+		return
+	}
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]string{"status": "revoked"})
+}
+
+func writeInviteError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, tttService.ErrInviteNotFound):
+		utils.WriteJSONError(w, http.StatusNotFound, "Invite not found.")
+	case errors.Is(err, tttService.ErrInviteExpired):
+		utils.WriteJSONError(w, http.StatusGone, "Invite has expired.")
+	case errors.Is(err, tttService.ErrInviteUsed):
+		utils.WriteJSONError(w, http.StatusGone, "Invite has already been used or revoked.")
+	default:
+		utils.WriteJSONError(w, http.StatusForbidden, "Invite failed: "+err.Error()+".")
+	}
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	tttService "github.com/Maiar0/tictactoe_backend/internal/tictactoe/service"
+	utils "github.com/Maiar0/tictactoe_backend/internal/utils"
+)
+
+const (
+	defaultLobbyPageSize = 20
+	maxLobbyPageSize     = 100
+)
+
+// lobbyEvent announces a game appearing in or leaving the lobby.
+type lobbyEvent struct {
+	Event  string                `json:"event"` // lobby_open or lobby_closed
+	GameID string                `json:"gameId"`
+	Game   *tttService.LobbyGame `json:"game,omitempty"`
+}
+
+type lobbyResp struct {
+	Games    []tttService.LobbyGame `json:"games"`
+	Page     int                    `json:"page"`
+	PageSize int                    `json:"pageSize"`
+	Total    int                    `json:"total"` // open games matching the filters
+}
+
+// listLobby handles GET /lobby?variant=&mode=&maxAge=<seconds>&page=&pageSize=
+func listLobby(w http.ResponseWriter, r *http.Request) {
+	log.Println("[listLobby] Request received: ", r.Method, r.URL.Path)
+	if r.Method != http.MethodGet {
+		utils.WriteJSONError(w, http.StatusMethodNotAllowed, "Method not Allowed.")
+		return
+	}
+	query := r.URL.Query()
+	variant, mode := query.Get("variant"), query.Get("mode")
+	if variant != "" && !tttService.IsVariant(variant) {
+		utils.WriteJSONError(w, http.StatusBadRequest, "Unknown variant.")
+		return
+	}
+	if mode != "" && !tttService.IsMode(mode) {
+		utils.WriteJSONError(w, http.StatusBadRequest, "Unknown mode.")
+		return
+	}
+	maxAge, err := queryInt(query.Get("maxAge"), 0)
+	if err != nil || maxAge < 0 {
+		utils.WriteJSONError(w, http.StatusBadRequest, "maxAge must be a number of seconds.")
+		return
+	}
+	page, err := queryInt(query.Get("page"), 1)
+	if err != nil || page < 1 {
+		utils.WriteJSONError(w, http.StatusBadRequest, "page must be 1 or more.")
+		return
+	}
+	pageSize, err := queryInt(query.Get("pageSize"), defaultLobbyPageSize)
+	if err != nil || pageSize < 1 || pageSize > maxLobbyPageSize {
+		utils.WriteJSONError(w, http.StatusBadRequest, "pageSize must be between 1 and "+strconv.Itoa(maxLobbyPageSize)+".")
+		return
+	}
+	games, err := tttService.ListLobby()
+	if err != nil {
+		utils.WriteJSONError(w, http.StatusInternalServerError, "Failed to list games.")
+		return
+	}
+	now := time.Now().Unix()
+	filtered := []tttService.LobbyGame{}
+	for _, game := range games {
+		if (variant != "" && game.Variant != variant) || (mode != "" && game.Mode != mode) {
+			continue
+		}
+		if maxAge > 0 && now-game.OpenedAt > int64(maxAge) {
+			continue
+		}
+		filtered = append(filtered, game)
+	}
+	start := min((page-1)*pageSize, len(filtered))
+	end := min(start+pageSize, len(filtered))
+	utils.WriteJSONResponse(w, http.StatusOK, lobbyResp{Games: filtered[start:end], Page: page, PageSize: pageSize, Total: len(filtered)})
+}
+
+func queryInt(value string, fallback int) (int, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// subscribeLobby adds a client to the lobby's live updates
+func subscribeLobby(playerUUID string) {
+	hub.SubscribeLobby(playerUUID, true)
+	log.Printf("Player %s watching the lobby", playerUUID)
+}
+
+// unsubscribeLobby removes a client from the lobby's live updates
+func unsubscribeLobby(playerUUID string) {
+	hub.SubscribeLobby(playerUUID, false)
+}
+
+// updateLobby tells lobby subscribers whether a game whose seats just
+// changed is now open or has filled.
+func updateLobby(gameID string) {
+	event := lobbyEvent{Event: "lobby_closed", GameID: gameID}
+	if game, ok := tttService.OpenGame(gameID); ok {
+		event = lobbyEvent{Event: "lobby_open", GameID: gameID, Game: &game}
+	}
+	out, err := encode(event)
+	if err != nil {
+		log.Printf("Failed to marshal message: %v", err)
+		return
+	}
+	hub.Send(delivery{lobby: true, out: out})
+}
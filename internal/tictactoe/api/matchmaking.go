@@ -0,0 +1,165 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	tttService "github.com/Maiar0/tictactoe_backend/internal/tictactoe/service"
+	tttStore "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
+	utils "github.com/Maiar0/tictactoe_backend/internal/utils"
+)
+
+var matchmaker = tttService.NewMatchmaker()
+
+func init() {
+	matchmaker.OnMatch = notifyMatch
+	matchmaker.OnTimeout = func(playerUUID string) {
+		SendToPlayer(playerUUID, gameEvent{Event: "queue_timeout", PlayerID: playerUUID})
+	}
+}
+
+// matchEvent tells a queued player which game they were put in.
+type matchEvent struct {
+	Event    string `json:"event"`
+	GameID   string `json:"gameId"`
+	Side     string `json:"side"`
+	Opponent string `json:"opponent"` // player UUID, or "ai" for the computer
+}
+
+// notifyMatch adds both players to the new game's group, tells each of them
+// their side and lets the computer open if it has X.
+func notifyMatch(match tttService.Match) {
+	for _, seat := range []struct{ player, side, opponent string }{
+		{match.PlayerX, "x", match.PlayerO},
+		{match.PlayerO, "o", match.PlayerX},
+	} {
+		if seat.player == tttService.AIPlayerID {
+			continue
+		}
+		if hub.Connected(seat.player) {
+			addPlayerToGame(seat.player, match.GameID)
+		}
+		SendToPlayer(seat.player, matchEvent{Event: "match_found", GameID: match.GameID, Side: seat.side, Opponent: seat.opponent})
+	}
+	_, played, err := playAITurn(match.GameID)
+	if err != nil || played {
+		return
+	}
+	if gameState, err := tttStore.GetGameState(match.GameID); err == nil {
+		broadcastGameState(match.GameID, gameState)
+	}
+}
+
+type joinQueueReq struct {
+	PlayerUUID string `json:"playerId"`
+	Side       string `json:"side"`       // x, o or empty for either
+	MinRating  int    `json:"minRating"`  // lowest opponent rating accepted; 0 for no limit
+	MaxRating  int    `json:"maxRating"`  // highest opponent rating accepted; 0 for no limit
+	Mode       string `json:"mode"`       // classic, ultimate or qubic; defaults to classic
+	Variant    string `json:"variant"`    // defaults to standard
+	Timeout    int    `json:"timeout"`    // seconds to wait; defaults to 60
+	AIFallback bool   `json:"aiFallback"` // play the computer when nobody is found
+	Bot        string `json:"bot"`        // bot for the fallback; defaults to medium
+}
+type queueResp struct {
+	Status string            `json:"status"` // queued, matched or idle
+	Match  *tttService.Match `json:"match,omitempty"`
+}
+
+func joinQueue(w http.ResponseWriter, r *http.Request) {
+	log.Println("[joinQueue] Request received: ", r.Method, r.URL.Path)
+	if r.Method != http.MethodPost {
+		utils.WriteJSONError(w, http.StatusMethodNotAllowed, "Method not Allowed.")
+		return
+	}
+	var req joinQueueReq
+	if err := utils.ReadRequestBody(w, r, &req); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "Bad Request")
+		return
+	}
+	if req.PlayerUUID == "" {
+		utils.WriteJSONError(w, http.StatusBadRequest, "Player UUID Required.")
+		return
+	}
+	entry := tttService.QueueEntry{
+		PlayerID:   req.PlayerUUID,
+		Side:       req.Side,
+		MinRating:  req.MinRating,
+		MaxRating:  req.MaxRating,
+		Mode:       req.Mode,
+		Variant:    req.Variant,
+		Timeout:    time.Duration(req.Timeout) * time.Second,
+		AIFallback: req.AIFallback,
+		Bot:        req.Bot,
+	}
+	if err := entry.Validate(); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "Failed to join queue: "+err.Error()+".")
+		return
+	}
+	match, matched, err := matchmaker.Join(entry)
+	if err != nil {
+		log.Println("[joinQueue] Failed to join queue: ", err)
+		utils.WriteJSONError(w, http.StatusInternalServerError, "Failed to join queue.")
+		return
+	}
+	if !matched {
+		utils.WriteJSONResponse(w, http.StatusAccepted, queueResp{Status: "queued"})
+		return
+	}
+	utils.WriteJSONResponse(w, http.StatusCreated, queueResp{Status: "matched", Match: &match})
+	log.Println("[joinQueue] Player ", req.PlayerUUID, " matched into game ", match.GameID)
+}
+
+type queueReq struct {
+	PlayerUUID string `json:"playerId"`
+}
+
+func leaveQueue(w http.ResponseWriter, r *http.Request) {
+	log.Println("[leaveQueue] Request received: ", r.Method, r.URL.Path)
+	if r.Method != http.MethodPost {
+		utils.WriteJSONError(w, http.StatusMethodNotAllowed, "Method not Allowed.")
+		return
+	}
+	var req queueReq
+	if err := utils.ReadRequestBody(w, r, &req); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "Bad Request")
+		return
+	}
+	if req.PlayerUUID == "" {
+		utils.WriteJSONError(w, http.StatusBadRequest, "Player UUID Required.")
+		return
+	}
+	if !matchmaker.Leave(req.PlayerUUID) {
+		utils.WriteJSONError(w, http.StatusNotFound, "Player is not in the queue.")
+		return
+	}
+	utils.WriteJSONResponse(w, http.StatusOK, queueResp{Status: "idle"})
+}
+
+// queueStatus lets clients without a websocket poll for their match.
+func queueStatus(w http.ResponseWriter, r *http.Request) {
+	log.Println("[queueStatus] Request received: ", r.Method, r.URL.Path)
+	if r.Method != http.MethodPost {
+		utils.WriteJSONError(w, http.StatusMethodNotAllowed, "Method not Allowed.")
+		return
+	}
+	var req queueReq
+	if err := utils.ReadRequestBody(w, r, &req); err != nil {
+		utils.WriteJSONError(w, http.StatusBadRequest, "Bad Request")
+		return
+	}
+	if req.PlayerUUID == "" {
+		utils.WriteJSONError(w, http.StatusBadRequest, "Player UUID Required.")
+		return
+	}
+	if matchmaker.Waiting(req.PlayerUUID) {
+		utils.WriteJSONResponse(w, http.StatusOK, queueResp{Status: "queued"})
+		return
+	}
+	if match, ok := matchmaker.LastMatch(req.PlayerUUID); ok {
+		utils.WriteJSONResponse(w, http.StatusOK, queueResp{Status: "matched", Match: &match})
+		return
+	}
+	utils.WriteJSONResponse(w, http.StatusOK, queueResp{Status: "idle"})
+}
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// WebSocket protocol
+//
+// A client picks the protocol version with the Sec-WebSocket-Protocol header.
+// Asking for "tictactoe.v1" selects protocol v1, where every frame in both
+// directions is an Envelope:
+//
+//	{"type": "register", "id": "7", "payload": {"playerId": "p1", "gameId": "abc123def"}}
+//
+// id is chosen by the client and echoed on the reply to that request, so
+// replies can be matched to requests. seq numbers the broadcasts of one
+// game in the order they were sent, and epoch names the run of the server
+// that numbered them: seqs start again from 1 after a restart. A client
+// whose connection dropped sends resume with the last seq and epoch it saw
+// to rejoin the game and have the missed broadcasts sent again. If too many
+// were missed, or the epoch is not the current one, it gets a resync
+// (resyncEvent) built from the stored game instead, whose seq is the point
+// the broadcasts that follow it continue from. Messages sent to a single
+// player are not replayed. A failed request gets a reply of type "error"
+// carrying a ProtocolError.
+//
+// Client messages (payload PlayerRequest unless noted):
+//
+//	heartbeat                                          -> heartbeat
+//	register, join_game, spectate                      -> registered, joined_game, spectating
+//	                                                      (register and join_game are followed by a game_state snapshot;
+//	                                                      join_game without a seat spectates and replies spectating)
+//	leave_game (gameId unused)                         -> left_game
+//	lobby_subscribe, lobby_unsubscribe (gameId unused) -> lobby_subscribed, lobby_unsubscribed
+//	create_game (newGameReq)                           -> game_created (newGameResp)
+//	choose_side (choosePlayerReq)                      -> side_chosen (choosePlayerResp)
+//	move (makeMoveReq)                                 -> move_made (makeMoveResp)
+//	get_game_state                                     -> game_state (getGameStateResp)
+//	resume (ResumeRequest)                             -> missed broadcasts, then resumed (resumeResp)
+//	takeback_request, takeback_accept, takeback_decline -> takeback_requested, takeback_accepted, takeback_declined
+//	draw_offer, draw_accept, draw_decline               -> draw_offered, draw_accepted, draw_declined
+//	resign                                             -> resigned
+//	rematch_request, rematch_accept, rematch_decline    -> rematch_requested, rematch_accepted, rematch_declined
+//
+// The takeback, draw, resign and rematch commands act for their playerId,
+// so they are only accepted on the connection that registered it. Their
+// replies follow the gameEvent telling the game what happened.
+//
+// Server messages besides replies: game_state (gameStateMessage), spectators
+// (spectatorEvent), lobby_open and lobby_closed (lobbyEvent), match_found
+// (matchEvent), rematch_started (rematchEvent) and the gameEvent
+// notifications such as draw_offered or takeback_accepted.
+//
+// A client that asks for no subprotocol gets the original format: requests
+// are {"playerId", "gameId", "message"}, replies are bare strings and events
+// are sent without an envelope. Commands that take more than a player and a
+// game read their fields from the top level of the request.
+
+// ProtocolV1 is the subprotocol name of protocol v1.
+const ProtocolV1 = "tictactoe.v1"
+
+// protocolPrefix marks subprotocols that name a version of this protocol.
+const protocolPrefix = "tictactoe."
+
+// Envelope is every protocol v1 frame.
+type Envelope struct {
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`    // request ID, echoed on its reply
+	Seq     int64           `json:"seq,omitempty"`   // set on game broadcasts, counting up from 1 per game
+	Epoch   string          `json:"epoch,omitempty"` // set with seq; seqs of different epochs are unrelated
+	Payload json.RawMessage `json:"payload,omitempty"`
+	Error   *ProtocolError  `json:"error,omitempty"`
+}
+
+// ProtocolError explains why a request failed.
+type ProtocolError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error codes
+const (
+	ErrBadRequest    = "bad_request"    // malformed frame or missing fields
+	ErrUnknownType   = "unknown_type"   // no such client message
+	ErrNotFound      = "not_found"      // no such game
+	ErrRejected      = "rejected"       // the game did not allow the action
+	ErrNotRegistered = "not_registered" // playerId is not registered on this connection
+	ErrInternal      = "internal"
+)
+
+// PlayerRequest is the payload of client messages about a player and a game.
+type PlayerRequest struct {
+	PlayerID string `json:"playerId"`
+	GameID   string `json:"gameId,omitempty"`
+}
+
+// serverMessage is a message in the shape each protocol version expects.
+type serverMessage struct {
+	Type    string
+	ID      string
+	Payload any
+	Error   *ProtocolError
+	Legacy  any // sent as-is to clients without a subprotocol; nil sends them nothing
+}
+
+// outgoing is a server message encoded once for every recipient. The hub
+// finishes the envelope once it knows the seq.
+type outgoing struct {
+	envelope Envelope
+	legacy   []byte
+}
+
+// typedMessage is implemented by events that name their own type.
+type typedMessage interface {
+	messageType() string
+}
+
+func (e gameEvent) messageType() string      { return e.Event }
+func (e spectatorEvent) messageType() string { return e.Event }
+func (e lobbyEvent) messageType() string     { return e.Event }
+func (e matchEvent) messageType() string     { return e.Event }
+func (e rematchEvent) messageType() string   { return e.Event }
+
+// toServerMessage wraps the values passed to SendToPlayer and SendToGame.
+// Bare strings become payload-free messages of that type. Anything else
+// without a type is a bug in the caller and is not sent.
+func toServerMessage(message any) (serverMessage, error) {
+	switch m := message.(type) {
+	case serverMessage:
+		return m, nil
+	case string:
+		return serverMessage{Type: m, Legacy: m}, nil
+	case typedMessage:
+		return serverMessage{Type: m.messageType(), Payload: m, Legacy: m}, nil
+	default:
+		return serverMessage{}, fmt.Errorf("server message %T has no type", message)
+	}
+}
+
+func encode(message any) (outgoing, error) {
+	m, err := toServerMessage(message)
+	if err != nil {
+		return outgoing{}, err
+	}
+	out := outgoing{envelope: Envelope{Type: m.Type, ID: m.ID, Error: m.Error}}
+	if m.Payload != nil {
+		payload, err := json.Marshal(m.Payload)
+		if err != nil {
+			return outgoing{}, err
+		}
+		out.envelope.Payload = payload
+	}
+	if m.Legacy != nil {
+		legacy, err := json.Marshal(m.Legacy)
+		if err != nil {
+			return outgoing{}, err
+		}
+		out.legacy = legacy
+	}
+	return out, nil
+}
+
+// unsupportedProtocol reports whether a client asked only for versions of
+// this protocol that the server does not speak. Unrelated subprotocols are
+// ignored, as they always were.
+func unsupportedProtocol(requested []string) bool {
+	asked := false
+	for _, protocol := range requested {
+		if protocol == ProtocolV1 {
+			return false
+		}
+		if strings.HasPrefix(protocol, protocolPrefix) {
+			asked = true
+		}
+	}
+	return asked
+}
+
+// command is a client message in either protocol version.
+type command struct {
+	Type    string
+	ID      string
+	Request PlayerRequest
+	Payload json.RawMessage // v1 payload, or the whole legacy message, for commands that take more than PlayerRequest
+}
+
+// parseCommand reads one client frame in the connection's protocol version.
+func parseCommand(protocol string, message []byte) (command, *ProtocolError) {
+	if protocol != ProtocolV1 {
+		var msg WebSocketMessage
+		if err := json.Unmarshal(message, &msg); err != nil {
+			return command{}, &ProtocolError{Code: ErrBadRequest, Message: "Invalid message format"}
+		}
+		return command{Type: msg.Message, Request: PlayerRequest{PlayerID: msg.PlayerUUID, GameID: msg.GameID}, Payload: message}, nil
+	}
+	var envelope Envelope
+	if err := json.Unmarshal(message, &envelope); err != nil || envelope.Type == "" {
+		return command{}, &ProtocolError{Code: ErrBadRequest, Message: "Frames must be envelopes with a type."}
+	}
+	cmd := command{Type: envelope.Type, ID: envelope.ID, Payload: envelope.Payload}
+	if len(envelope.Payload) > 0 {
+		if err := json.Unmarshal(envelope.Payload, &cmd.Request); err != nil {
+			return cmd, &ProtocolError{Code: ErrBadRequest, Message: "Invalid payload: " + err.Error()}
+		}
+	}
+	return cmd, nil
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"sync"
+
+	tttService "github.com/Maiar0/tictactoe_backend/internal/tictactoe/service"
+	tttStore "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
+)
+
+// rematchEvent tells both players the rematch has been created.
+type rematchEvent struct {
+	Event     string                 `json:"event"`
+	GameID    string                 `json:"gameId"`    // finished game
+	NewGameID string                 `json:"newGameId"` // rematch with sides swapped
+	Series    tttService.SeriesScore `json:"series"`
+}
+
+// pendingRematches maps a finished game ID to the player who asked for a rematch.
+var (
+	pendingRematches   = make(map[string]string)
+	pendingRematchesMu sync.Mutex
+)
+
+// requestRematch asks the opponent for a rematch of a finished game.
+// The computer always agrees, so against it the rematch starts at once, as
+// it does when the opponent has already asked.
+func requestRematch(playerUUID, gameID string) *requestError {
+	log.Println("[requestRematch] Player ", playerUUID, " asking for a rematch of game ", gameID)
+	gameState, reqErr := currentGame(gameID)
+	if reqErr != nil {
+		return reqErr
+	}
+	if gameState.Status == tttService.StatusActive {
+		return rejectRequest(http.StatusForbidden, "Game is still in progress.")
+	}
+	if gameState.Config.NextGame != "" {
+		return rejectRequest(http.StatusForbidden, "Rematch already started: "+gameState.Config.NextGame+".")
+	}
+	opponent := tttService.Opponent(gameState, playerUUID)
+	if opponent == "" {
+		return rejectRequest(http.StatusForbidden, "You are not playing in this game.")
+	}
+	if opponent == tttService.AIPlayerID {
+		return startRematch(playerUUID, gameID)
+	}
+	pendingRematchesMu.Lock()
+	requester, crossed := pendingRematches[gameID]
+	crossed = crossed && requester == opponent
+	if crossed {
+		delete(pendingRematches, gameID)
+	} else {
+		pendingRematches[gameID] = playerUUID
+	}
+	pendingRematchesMu.Unlock()
+	if crossed {
+		//both asked, so each request accepts the other
+		return startRematch(requester, gameID)
+	}
+	SendToGame(gameID, gameEvent{Event: "rematch_requested", GameID: gameID, PlayerID: playerUUID})
+	return nil
+}
+
+// answerRematch accepts or declines the pending rematch on behalf of the opponent.
+func answerRematch(playerUUID, gameID string, accept bool) *requestError {
+	log.Println("[answerRematch] Player ", playerUUID, " answering rematch of game ", gameID, ": ", accept)
+	gameState, reqErr := currentGame(gameID)
+	if reqErr != nil {
+		return reqErr
+	}
+	pendingRematchesMu.Lock()
+	requester, ok := pendingRematches[gameID]
+	if ok && tttService.Opponent(gameState, requester) == playerUUID {
+		delete(pendingRematches, gameID)
+	} else {
+		ok = false
+	}
+	pendingRematchesMu.Unlock()
+	if !ok {
+		return rejectRequest(http.StatusForbidden, "No rematch to answer.")
+	}
+	if !accept {
+		SendToGame(gameID, gameEvent{Event: "rematch_declined", GameID: gameID, PlayerID: requester})
+		return nil
+	}
+	return startRematch(requester, gameID)
+}
+
+// startRematch creates the rematch, moves the players' websockets over to
+// it and tells them the new game ID.
+func startRematch(requester, gameID string) *requestError {
+	newGameID, err := tttService.Rematch(gameID)
+	if err != nil {
+		log.Println("[startRematch] Rematch failed: ", err)
+		return rejectRequest(http.StatusForbidden, "Rematch failed: "+err.Error()+".")
+	}
+	series, err := tttService.Series(newGameID)
+	if err != nil {
+		log.Println("[startRematch] Failed to score series: ", err)
+	}
+	for _, playerUUID := range hub.Players(gameID) {
+		addPlayerToGame(playerUUID, newGameID)
+	}
+	SendToGame(gameID, rematchEvent{Event: "rematch_started", GameID: gameID, NewGameID: newGameID, Series: series})
+	//the computer may have white after the swap
+	if _, played, err := playAITurn(newGameID); err == nil && !played {
+		gameState, err := tttStore.GetGameState(newGameID)
+		if err == nil {
+			broadcastGameState(newGameID, gameState)
+		}
+	}
+	return nil
+}
@@ -7,9 +7,19 @@ import (
 
 func Register(mux *http.ServeMux) {
 	log.Printf("[Register] tictactoe api endpoints")
-	mux.HandleFunc("/api/v1/tictactoe/create", newGame)             // POST
-	mux.HandleFunc("/api/v1/tictactoe/state", getGameState)         // POST
-	mux.HandleFunc("/api/v1/tictactoe/move", makeMove)              // POST
-	mux.HandleFunc("/api/v1/tictactoe/choose_player", choosePlayer) // POST
+	mux.HandleFunc("/api/v1/tictactoe/create", newGame)                 // POST
+	mux.HandleFunc("/api/v1/tictactoe/state", getGameState)             // POST
+	mux.HandleFunc("/api/v1/tictactoe/move", makeMove)                  // POST
+	mux.HandleFunc("/api/v1/tictactoe/choose_player", choosePlayer)     // POST
+	mux.HandleFunc("/api/v1/tictactoe/history", getHistory)             // POST
+	mux.HandleFunc("/api/v1/tictactoe/replay", replay)                  // POST
+	mux.HandleFunc("/api/v1/tictactoe/invite/create", createInvite)     // POST
+	mux.HandleFunc("/api/v1/tictactoe/invite/accept", acceptInvite)     // POST
+	mux.HandleFunc("/api/v1/tictactoe/invite/revoke", revokeInvite)     // POST
+	mux.HandleFunc("/api/v1/tictactoe/matchmaking/join", joinQueue)     // POST
+	mux.HandleFunc("/api/v1/tictactoe/matchmaking/leave", leaveQueue)   // POST
+	mux.HandleFunc("/api/v1/tictactoe/matchmaking/status", queueStatus) // POST
+	mux.HandleFunc("/api/v1/tictactoe/lobby", listLobby)                // GET
+	mux.HandleFunc("/api/v1/tictactoe/bots", listBots)                  // GET
 	mux.HandleFunc("/ws", HandleWebSocket)
 }
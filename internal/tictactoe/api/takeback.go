@@ -0,0 +1,92 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"sync"
+
+	tttService "github.com/Maiar0/tictactoe_backend/internal/tictactoe/service"
+)
+
+// gameEvent is a websocket notification about something other than a move.
+type gameEvent struct {
+	Event    string `json:"event"`
+	GameID   string `json:"gameId"`
+	PlayerID string `json:"playerId,omitempty"` // player the event is about
+	Error    string `json:"error,omitempty"`
+}
+
+// pendingTakebacks maps a game ID to the player waiting on the opponent's answer.
+var (
+	pendingTakebacks   = make(map[string]string)
+	pendingTakebacksMu sync.Mutex
+)
+
+// requestTakeback asks the opponent to let playerUUID undo their last move.
+// The computer always agrees, so against it the takeback happens at once.
+func requestTakeback(playerUUID, gameID string) *requestError {
+	log.Println("[requestTakeback] Player ", playerUUID, " asking for a takeback in game ", gameID)
+	gameState, reqErr := currentGame(gameID)
+	if reqErr != nil {
+		return reqErr
+	}
+	if gameState.Status != tttService.StatusActive {
+		return rejectRequest(http.StatusForbidden, "Game is not in progress.")
+	}
+	opponent := tttService.Opponent(gameState, playerUUID)
+	if opponent == "" {
+		return rejectRequest(http.StatusForbidden, "You are not playing in this game.")
+	}
+	if opponent == tttService.AIPlayerID {
+		return applyTakeback(playerUUID, gameID)
+	}
+	pendingTakebacksMu.Lock()
+	pendingTakebacks[gameID] = playerUUID
+	pendingTakebacksMu.Unlock()
+	SendToGame(gameID, gameEvent{Event: "takeback_requested", GameID: gameID, PlayerID: playerUUID})
+	return nil
+}
+
+// answerTakeback accepts or declines the pending takeback in gameID on behalf of the opponent.
+func answerTakeback(playerUUID, gameID string, accept bool) *requestError {
+	log.Println("[answerTakeback] Player ", playerUUID, " answering takeback in game ", gameID, ": ", accept)
+	gameState, reqErr := currentGame(gameID)
+	if reqErr != nil {
+		return reqErr
+	}
+	pendingTakebacksMu.Lock()
+	requester, ok := pendingTakebacks[gameID]
+	if ok && tttService.Opponent(gameState, requester) == playerUUID {
+		delete(pendingTakebacks, gameID)
+	} else {
+		ok = false
+	}
+	pendingTakebacksMu.Unlock()
+	if !ok {
+		return rejectRequest(http.StatusForbidden, "No takeback to answer.")
+	}
+	if !accept {
+		SendToGame(gameID, gameEvent{Event: "takeback_declined", GameID: gameID, PlayerID: requester})
+		return nil
+	}
+	return applyTakeback(requester, gameID)
+}
+
+// applyTakeback rolls the game back and tells everyone in it.
+func applyTakeback(requester, gameID string) *requestError {
+	gameState, err := tttService.Takeback(gameID, requester)
+	if err != nil {
+		log.Println("[applyTakeback] Takeback failed: ", err)
+		return rejectRequest(http.StatusForbidden, "Takeback failed: "+err.Error()+".")
+	}
+	SendToGame(gameID, gameEvent{Event: "takeback_accepted", GameID: gameID, PlayerID: requester})
+	broadcastGameState(gameID, gameState)
+	return nil
+}
+
+// clearTakeback drops any pending takeback once the game moves on.
+func clearTakeback(gameID string) {
+	pendingTakebacksMu.Lock()
+	delete(pendingTakebacks, gameID)
+	pendingTakebacksMu.Unlock()
+}
@@ -5,81 +5,141 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
 
 var upgrader = websocket.Upgrader{
+	Subprotocols: []string{ProtocolV1},
 	CheckOrigin: func(r *http.Request) bool {
 		return true // Allow all origins for development
 	},
 }
 
-// Track connections by Player UUID
-var playerConnections = make(map[string]*websocket.Conn)
-
-// Track players in each game
-var gameGroups = make(map[string][]string)
-
-// addClient adds a client to the playerConnections map
-func addClient(conn *websocket.Conn, playerUUID string) {
+// spectatorEvent tells a game group how many spectators are watching
+type spectatorEvent struct {
+	Event      string `json:"event"`
+	GameID     string `json:"gameId"`
+	Spectators int    `json:"spectators"`
+}
 
-	playerConnections[playerUUID] = conn
+// addClient binds a player UUID to a connection
+func addClient(c *client, playerUUID string) {
+	hub.Register(c, playerUUID)
 	log.Printf("Player %s connected/reconnected", playerUUID)
 }
 
-// removeClient removes a client from the playerConnections map
+// removeClient drops a player's connection binding and every room they are in
 func removeClient(playerUUID string) {
-	delete(playerConnections, playerUUID)
-	for gameID, players := range gameGroups {
-		for i, player := range players {
-			if player == playerUUID {
-				gameGroups[gameID] = append(players[:i], players[i+1:]...)
-				break
-			}
-		}
+	left := hub.Leave(playerUUID)
+	for _, gameID := range left.spectated {
+		broadcastSpectators(gameID)
+	}
+}
+
+// addSpectatorToGame subscribes a client to a game's broadcasts without a seat
+func addSpectatorToGame(playerUUID, gameID string) {
+	if !hub.Spectate(playerUUID, gameID) {
+		return
 	}
+	log.Printf("Spectator %s watching game %s", playerUUID, gameID)
+	broadcastSpectators(gameID)
+}
+
+// isSpectator reports whether a client is watching a game as a spectator
+func isSpectator(playerUUID, gameID string) bool {
+	return hub.IsSpectator(playerUUID, gameID)
+}
+
+// broadcastSpectators sends the live spectator count to everyone in the game
+func broadcastSpectators(gameID string) {
+	SendToGame(gameID, spectatorEvent{Event: "spectators", GameID: gameID, Spectators: hub.SpectatorCount(gameID)})
+}
+
+// gamesOf lists the games a player is in
+func gamesOf(playerUUID string) []string {
+	return hub.GamesOf(playerUUID)
 }
 
 // addPlayerToGame adds a player to a game
 func addPlayerToGame(playerUUID, gameID string) {
-	if gameGroups[gameID] == nil {
-		gameGroups[gameID] = []string{}
-	}
-	gameGroups[gameID] = append(gameGroups[gameID], playerUUID)
+	hub.Join(playerUUID, gameID)
 	log.Printf("Player %s added to game %s", playerUUID, gameID)
 }
 
 func SendToPlayer(playerUUID string, message any) {
 	log.Printf("[SendToPlayer] Sending to player %s: %+v", playerUUID, message)
-	if conn, exists := playerConnections[playerUUID]; exists {
-		jsonMsg, err := json.Marshal(message)
-		if err != nil {
-			log.Printf("Failed to marshal message: %v", err)
-			return
-		}
-		conn.WriteMessage(websocket.TextMessage, jsonMsg)
+	out, err := encode(message)
+	if err != nil {
+		log.Printf("Failed to marshal message: %v", err)
+		return
 	}
+	hub.Send(delivery{playerUUID: playerUUID, out: out})
 }
 
 func SendToGame(gameID string, message any) {
 	log.Printf("[SendToGame] Sending to game: %+v", message)
-	if players, exists := gameGroups[gameID]; exists {
-		jsonMsg, err := json.Marshal(message)
-		if err != nil {
-			log.Printf("Failed to marshal message: %v", err)
-			return
-		}
-		for _, playerUUID := range players {
-			if conn, exists := playerConnections[playerUUID]; exists {
-				conn.WriteMessage(websocket.TextMessage, jsonMsg)
-			}
-		}
+	out, err := encode(message)
+	if err != nil {
+		log.Printf("Failed to marshal message: %v", err)
+		return
+	}
+	hub.Send(delivery{gameID: gameID, out: out})
+}
+
+// SendStateToGame broadcasts a game_state message. Clients without a
+// subprotocol get the state as a JSON string, as they always have.
+func SendStateToGame(gameID string, state any) {
+	stateJSON, err := json.Marshal(state)
+	if err != nil {
+		log.Printf("Failed to marshal message: %v", err)
+		return
+	}
+	SendToGame(gameID, serverMessage{Type: "game_state", Payload: json.RawMessage(stateJSON), Legacy: string(stateJSON)})
+}
+
+// sendToConn replies on a connection whether or not a player is registered on it
+func sendToConn(c *client, message any) {
+	out, err := encode(message)
+	if err != nil {
+		log.Printf("Failed to marshal message: %v", err)
+		return
+	}
+	hub.Send(delivery{client: c, out: out})
+}
+
+// reply answers a command on its connection, echoing its request ID.
+// Clients without a subprotocol get the payload, or the bare type if there is none.
+func reply(c *client, cmd command, replyType string, payload any) {
+	legacy := any(replyType)
+	if payload != nil {
+		legacy = payload
+	}
+	sendToConn(c, serverMessage{Type: replyType, ID: cmd.ID, Payload: payload, Legacy: legacy})
+}
+
+// replyError answers a command with a structured error
+func replyError(c *client, cmd command, code, message string) {
+	log.Printf("[replyError] %s %s: %s", cmd.Type, code, message)
+	protocolError := &ProtocolError{Code: code, Message: message}
+	legacy := any(gameEvent{Event: "error", GameID: cmd.Request.GameID, PlayerID: cmd.Request.PlayerID, Error: message})
+	switch code {
+	case ErrBadRequest:
+		legacy = message
+	case ErrUnknownType:
+		legacy = "unknown_message"
 	}
+	sendToConn(c, serverMessage{Type: "error", ID: cmd.ID, Error: protocolError, Legacy: legacy})
 }
 
 func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	log.Println("[HandleWebSocket] Request received: ", r.Method, r.URL.Path)
+	if unsupportedProtocol(websocket.Subprotocols(r)) {
+		log.Printf("Unsupported protocol versions: %v", websocket.Subprotocols(r))
+		http.Error(w, "Unsupported protocol version. Supported: "+ProtocolV1, http.StatusBadRequest)
+		return
+	}
 	// Upgrade HTTP to WebSocket
 	ws, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -88,18 +148,20 @@ func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("Remote address: %s", ws.RemoteAddr()) // Client IP:port
 	log.Printf("Subprotocol: %s", ws.Subprotocol())   // If specified
+	c := newClient(ws)
+	//a peer that goes quiet without closing is dropped once it stops answering pings
+	ws.SetReadDeadline(time.Now().Add(pongWait))
+	ws.SetPongHandler(func(string) error { return ws.SetReadDeadline(time.Now().Add(pongWait)) })
+	go c.writePump()
 	//handle heartbeat death
 	defer func() {
-		// Find which player this connection belongs to
-		for playerUUID, conn := range playerConnections { //TODO:: less effecient than creatign another map
-			if conn == ws {
-				log.Printf("Connection died, cleaning up player: %s", playerUUID)
-				removeClient(playerUUID)
-				break
+		for _, left := range hub.Unregister(c) {
+			log.Printf("Connection died, cleaning up player: %s", left.playerUUID)
+			for _, gameID := range left.spectated {
+				broadcastSpectators(gameID)
 			}
+			scheduleAbandonment(left.playerUUID, left.games)
 		}
-		ws.Close()
-
 	}()
 
 	// Handle WebSocket connection
@@ -110,50 +172,117 @@ func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 			log.Printf("WebSocket read error: %v", err)
 			break
 		}
+		ws.SetReadDeadline(time.Now().Add(pongWait))
 
-		// Check if it's a heartbeat message and ignores it
-		if strings.Contains(string(message), "heartbeat") {
+		// Check if it's a legacy heartbeat message and ignores it
+		if c.protocol != ProtocolV1 && strings.Contains(string(message), "heartbeat") {
 			continue
 		}
 
 		log.Printf("Received: %s", string(message))
 
 		// Send response
-		handleWebSocketMessage(ws, string(message))
+		handleWebSocketMessage(c, message)
 	}
 }
 
+// WebSocketMessage is a client message of the original, unversioned protocol.
 type WebSocketMessage struct {
 	PlayerUUID string `json:"playerId"`
 	GameID     string `json:"gameId"`
 	Message    string `json:"message"`
 }
 
-func handleWebSocketMessage(ws *websocket.Conn, message string) {
-	var msg WebSocketMessage
-	if err := json.Unmarshal([]byte(message), &msg); err != nil {
-		log.Printf("Failed to unmarshal WebSocket message: %v", err)
-		ws.WriteMessage(websocket.TextMessage, []byte("Invalid message format"))
+// accepting and declining fix the answer of a takeback, draw or rematch reply.
+func accepting(answer func(playerUUID, gameID string, accept bool) *requestError) func(playerUUID, gameID string) *requestError {
+	return func(playerUUID, gameID string) *requestError { return answer(playerUUID, gameID, true) }
+}
+
+func declining(answer func(playerUUID, gameID string, accept bool) *requestError) func(playerUUID, gameID string) *requestError {
+	return func(playerUUID, gameID string) *requestError { return answer(playerUUID, gameID, false) }
+}
+
+// actsForPlayer lists the commands that act on a game for the player they
+// name, who must be registered on the connection that sends them.
+var actsForPlayer = map[string]bool{
+	"takeback_request": true, "takeback_accept": true, "takeback_decline": true,
+	"resign": true, "draw_offer": true, "draw_accept": true, "draw_decline": true,
+	"rematch_request": true, "rematch_accept": true, "rematch_decline": true,
+}
+
+func handleWebSocketMessage(c *client, message []byte) {
+	cmd, protocolError := parseCommand(c.protocol, message)
+	if protocolError != nil {
+		log.Printf("Failed to unmarshal WebSocket message: %s", protocolError.Message)
+		replyError(c, cmd, protocolError.Code, protocolError.Message)
+		return
+	}
+	log.Printf("[WebSocket]Received: %+v", cmd)
+	playerUUID, gameID := cmd.Request.PlayerID, cmd.Request.GameID
+	if playerUUID == "" && cmd.Type != "heartbeat" {
+		replyError(c, cmd, ErrBadRequest, "playerId is required.")
+		return
+	}
+	if actsForPlayer[cmd.Type] && !hub.Bound(c, playerUUID) {
+		replyError(c, cmd, ErrNotRegistered, "Register as this player on this connection first.")
 		return
 	}
-	log.Printf("[WebSocket]Received: %+v", msg)
-	switch msg.Message {
+	switch cmd.Type {
 	case "heartbeat":
-		SendToPlayer(msg.PlayerUUID, "heartbeat")
+		reply(c, cmd, "heartbeat", nil)
 	case "register":
-		addClient(ws, msg.PlayerUUID)
-		addPlayerToGame(msg.PlayerUUID, msg.GameID)
-		SendToPlayer(msg.PlayerUUID, "registered")
+		addClient(c, playerUUID)
+		addPlayerToGame(playerUUID, gameID)
+		reply(c, cmd, "registered", nil)
+		pushSnapshot(c, playerUUID, gameID)
 	case "join_game":
-		addPlayerToGame(msg.PlayerUUID, msg.GameID)
-		SendToPlayer(msg.PlayerUUID, "joined_game")
+		wsJoinGame(c, cmd)
+	case "lobby_subscribe":
+		addClient(c, playerUUID)
+		subscribeLobby(playerUUID)
+		reply(c, cmd, "lobby_subscribed", nil)
+	case "lobby_unsubscribe":
+		unsubscribeLobby(playerUUID)
+		reply(c, cmd, "lobby_unsubscribed", nil)
+	case "spectate":
+		addClient(c, playerUUID)
+		reply(c, cmd, "spectating", nil)
+		addSpectatorToGame(playerUUID, gameID)
 	case "leave_game":
-		removeClient(msg.PlayerUUID)
-		SendToPlayer(msg.PlayerUUID, "left_game")
+		removeClient(playerUUID)
+		reply(c, cmd, "left_game", nil)
+	case "create_game":
+		wsCreateGame(c, cmd)
+	case "choose_side":
+		wsChooseSide(c, cmd)
+	case "move":
+		wsMove(c, cmd)
 	case "get_game_state":
-		SendToPlayer(msg.PlayerUUID, "game_state")
+		wsGetGameState(c, cmd)
+	case "resume":
+		wsResume(c, cmd)
+	case "takeback_request":
+		wsGameAction(c, cmd, "takeback_requested", requestTakeback)
+	case "takeback_accept":
+		wsGameAction(c, cmd, "takeback_accepted", accepting(answerTakeback))
+	case "takeback_decline":
+		wsGameAction(c, cmd, "takeback_declined", declining(answerTakeback))
+	case "resign":
+		wsGameAction(c, cmd, "resigned", resign)
+	case "draw_offer":
+		wsGameAction(c, cmd, "draw_offered", offerDraw)
+	case "draw_accept":
+		wsGameAction(c, cmd, "draw_accepted", accepting(answerDraw))
+	case "draw_decline":
+		wsGameAction(c, cmd, "draw_declined", declining(answerDraw))
+	case "rematch_request":
+		wsGameAction(c, cmd, "rematch_requested", requestRematch)
+	case "rematch_accept":
+		wsGameAction(c, cmd, "rematch_accepted", accepting(answerRematch))
+	case "rematch_decline":
+		wsGameAction(c, cmd, "rematch_declined", declining(answerRematch))
 	default:
-		log.Printf("Unknown message: %s", msg.Message)
-		SendToPlayer(msg.PlayerUUID, "unknown_message")
+		log.Printf("Unknown message: %s", cmd.Type)
+		replyError(c, cmd, ErrUnknownType, "Unknown message type: "+cmd.Type+".")
 	}
 }
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"errors"
+	"log"
+
+	store "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
+)
+
+// AIPlayerID is stored in the PlayerX/PlayerO seat taken by the computer.
+const AIPlayerID = "ai"
+
+// SeatAI puts the computer on whichever side the human did not choose.
+func SeatAI(gameState store.GameState) store.GameState {
+	if gameState.PlayerX == "" {
+		gameState.PlayerX = AIPlayerID
+	} else if gameState.PlayerO == "" {
+		gameState.PlayerO = AIPlayerID
+	}
+	log.Println("[SeatAI] AI seated: ", gameState)
+	return gameState
+}
+
+// IsAITurn reports whether the game is still running and the side to move is the computer.
+func IsAITurn(gameState store.GameState) bool {
+	if !gameState.Config.IsAi || gameState.Status != StatusActive {
+		return false
+	}
+	if gameState.PlayerX == "" || gameState.PlayerO == "" {
+		return false
+	}
+	if Turn(gameState.State) == 'x' {
+		return gameState.PlayerX == AIPlayerID
+	}
+	return gameState.PlayerO == AIPlayerID
+}
+
+// PlayAIMove makes the computer's move if it is its turn and returns the resulting state.
+// played is false when there was nothing for the computer to do.
+func PlayAIMove(gameID string) (result store.GameState, played bool, err error) {
+	unlock := lockGame(gameID)
+	defer unlock()
+	gameState, err := store.GetGameState(gameID)
+	if err != nil {
+		log.Println("[PlayAIMove] Failed to get game state: ", err)
+		return store.GameState{}, false, err
+	}
+	if !IsAITurn(gameState) {
+		return gameState, false, nil
+	}
+	name := gameState.Config.Bot
+	if name == "" {
+		name = DefaultBot
+	}
+	bot, ok := GetBot(name)
+	if !ok {
+		log.Println("[PlayAIMove] Unknown bot: ", name)
+		return store.GameState{}, false, errors.New("unknown bot: " + name)
+	}
+	move, err := bot.Move(gameState)
+	if err == nil {
+		err = ValidateMove(gameState, move)
+	}
+	if err != nil {
+		log.Println("[PlayAIMove] Bot misbehaved, forfeiting: ", name, " ", err)
+		result, err = forfeitAI(gameID, gameState)
+		if err != nil {
+			return store.GameState{}, false, err
+		}
+		return result, true, nil
+	}
+	log.Println("[PlayAIMove] AI playing: ", move, " in game: ", gameID)
+	result, err = makeMove(gameID, AIPlayerID, move)
+	if err != nil {
+		return store.GameState{}, false, err
+	}
+	return result, true, nil
+}
+
+// forfeitAI ends the game in favour of the human because the bot failed to move.
+func forfeitAI(gameID string, gameState store.GameState) (store.GameState, error) {
+	if gameState.PlayerX == AIPlayerID {
+		gameState.Status = EndStatus(gameState.PlayerO, ReasonForfeit)
+	} else {
+		gameState.Status = EndStatus(gameState.PlayerX, ReasonForfeit)
+	}
+	if err := store.UpdateGameState(gameID, gameState); err != nil {
+		log.Println("[forfeitAI] Failed to update game state: ", err)
+		return store.GameState{}, err
+	}
+	return gameState, nil
+}
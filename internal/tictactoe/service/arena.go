@@ -0,0 +1,50 @@
+package service
+
+import (
+	store "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
+)
+
+// MatchResult is the outcome of one offline game between two bots.
+type MatchResult struct {
+	Winner  byte     // 'x', 'o' or '.' for a draw
+	Forfeit bool     // the loser failed to produce a legal move
+	Moves   []string // every move played, in order
+}
+
+// PlayMatch plays a full game between two bots in memory using the same
+// rules as MakeMove, without touching the store. A bot that errors or
+// answers with an illegal move forfeits.
+func PlayMatch(config store.GameConfig, botX, botO Bot) MatchResult {
+	var result MatchResult
+	config.IsAi = true
+	rules := RulesFor(config)
+	variant := VariantFor(config)
+	gameState := store.GameState{State: rules.InitialState(), Status: StatusActive, PlayerX: AIPlayerID, PlayerO: AIPlayerID, Config: config}
+	for {
+		turn := Turn(gameState.State)
+		bot := botX
+		if turn == 'o' {
+			bot = botO
+		}
+		move, err := bot.Move(gameState)
+		if err == nil {
+			err = ValidateMove(gameState, move)
+		}
+		if err != nil {
+			result.Winner = otherPiece(turn)
+			result.Forfeit = true
+			return result
+		}
+		result.Moves = append(result.Moves, move)
+		piece, position, _ := rules.ParseMove(move)
+		gameState = alterGameState(gameState, piece, position)
+		if gameWon(rules, gameState.State, position) {
+			result.Winner = variant.Winner(turn)
+			return result
+		}
+		if gameTied(rules, gameState.State) {
+			result.Winner = '.'
+			return result
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	store "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
+)
+
+// Board is the geometry of a game: a Rows x Cols grid where K in a row wins.
+// Cells are numbered row by row starting at 0, so on the classic board
+// the center is 4 and on a 15x15 board it is 112.
+type Board struct {
+	Rows int
+	Cols int
+	K    int
+}
+
+// ClassicBoard is standard 3x3 tic-tac-toe.
+var ClassicBoard = Board{Rows: 3, Cols: 3, K: 3}
+
+// MaxBoardSide caps Rows and Cols.
+const MaxBoardSide = 30
+
+// boardGeometry holds the winning lines generated for one Board.
+type boardGeometry struct {
+	lines        [][]int
+	linesThrough [][][]int // linesThrough[pos] are the lines containing pos
+}
+
+var (
+	geometriesMu sync.Mutex
+	geometries   = map[Board]*boardGeometry{}
+)
+
+// BoardFor returns the geometry stored in the game's config.
+func BoardFor(config store.GameConfig) Board {
+	return Board{Rows: config.Rows, Cols: config.Cols, K: config.K}
+}
+
+// BoardRows splits a game's cells into rows for display. Ultimate boards come
+// back as their nine sub-boards, each numbered row by row, and qubic as its
+// sixteen rows, layer by layer.
+func BoardRows(gameState store.GameState) []string {
+	rules := RulesFor(gameState.Config)
+	width := gameState.Config.Cols
+	switch gameState.Config.Mode {
+	case ModeUltimate:
+		width = 9
+	case ModeQubic:
+		width = qubicSize
+	}
+	if width < 1 || len(gameState.State) < rules.Cells() {
+		return nil
+	}
+	var rows []string
+	for start := 0; start < rules.Cells(); start += width {
+		rows = append(rows, gameState.State[start:min(start+width, rules.Cells())])
+	}
+	return rows
+}
+
+// Validate checks that the board fits the limits and can be won.
+func (b Board) Validate() error {
+	if b.Rows < 1 || b.Cols < 1 || b.Rows > MaxBoardSide || b.Cols > MaxBoardSide {
+		return fmt.Errorf("rows and cols must be between 1 and %d", MaxBoardSide)
+	}
+	if b.K < 2 || (b.K > b.Rows && b.K > b.Cols) {
+		return errors.New("k must be at least 2 and fit on the board")
+	}
+	return nil
+}
+
+// Cells is the number of squares on the board.
+func (b Board) Cells() int {
+	return b.Rows * b.Cols
+}
+
+// InitialState is the state string for a new game with this geometry:
+// every cell empty followed by the turn marker.
+func (b Board) InitialState() string {
+	return strings.Repeat(".", b.Cells()) + "X"
+}
+
+// geometry generates (once) every horizontal, vertical and diagonal run of K cells.
+func (b Board) geometry() *boardGeometry {
+	geometriesMu.Lock()
+	defer geometriesMu.Unlock()
+	if g, ok := geometries[b]; ok {
+		return g
+	}
+	g := &boardGeometry{linesThrough: make([][][]int, b.Cells())}
+	directions := [4][2]int{{0, 1}, {1, 0}, {1, 1}, {1, -1}}
+	for row := 0; row < b.Rows; row++ {
+		for col := 0; col < b.Cols; col++ {
+			for _, d := range directions {
+				endRow, endCol := row+d[0]*(b.K-1), col+d[1]*(b.K-1)
+				if endRow < 0 || endRow >= b.Rows || endCol < 0 || endCol >= b.Cols {
+					continue
+				}
+				line := make([]int, b.K)
+				for i := range line {
+					line[i] = (row+d[0]*i)*b.Cols + col + d[1]*i
+				}
+				g.lines = append(g.lines, line)
+				for _, pos := range line {
+					g.linesThrough[pos] = append(g.linesThrough[pos], line)
+				}
+			}
+		}
+	}
+	geometries[b] = g
+	return g
+}
+
+// lines returns every winning line on the board.
+func (b Board) lines() [][]int {
+	return b.geometry().lines
+}
+
+// lineThrough returns the line player completed through pos, if any.
+func (b Board) lineThrough(cells []byte, player byte, pos int) ([]int, bool) {
+	return b.geometry().lineThrough(cells, player, pos)
+}
+
+// lineThrough returns the line player completed through pos, if any.
+func (g *boardGeometry) lineThrough(cells []byte, player byte, pos int) ([]int, bool) {
+	for _, line := range g.linesThrough[pos] {
+		if ownsLine(cells, line, player) {
+			return line, true
+		}
+	}
+	return nil, false
+}
+
+// hasLine reports whether player owns any full line.
+func (b Board) hasLine(cells []byte, player byte) bool {
+	for _, line := range b.lines() {
+		if ownsLine(cells, line, player) {
+			return true
+		}
+	}
+	return false
+}
+
+func ownsLine(cells []byte, line []int, player byte) bool {
+	for _, pos := range line {
+		if cells[pos] != player {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseMove reads a move as a piece followed by either a cell index ("x4",
+// "o112") or a zero based row and column ("x7,7").
+func (b Board) ParseMove(move string) (byte, int, error) {
+	if len(move) < 2 {
+		return 0, 0, errors.New("malformed move: " + move)
+	}
+	piece := strings.ToLower(move[:1])[0]
+	if piece != 'x' && piece != 'o' {
+		return 0, 0, errors.New("malformed move: " + move)
+	}
+	var position int
+	if row, col, ok := strings.Cut(move[1:], ","); ok {
+		r, err1 := strconv.Atoi(row)
+		c, err2 := strconv.Atoi(col)
+		if err1 != nil || err2 != nil || r < 0 || r >= b.Rows || c < 0 || c >= b.Cols {
+			return 0, 0, errors.New("malformed move: " + move)
+		}
+		position = r*b.Cols + c
+	} else {
+		p, err := strconv.Atoi(move[1:])
+		if err != nil || p < 0 || p >= b.Cells() {
+			return 0, 0, errors.New("malformed move: " + move)
+		}
+		position = p
+	}
+	return piece, position, nil
+}
+
+// LegalMoves is every open cell.
+func (b Board) LegalMoves(state []byte) []int {
+	return openSquares(state[:b.Cells()])
+}
+
+// Place puts piece at pos and passes the turn.
+func (b Board) Place(state []byte, piece byte, pos int) {
+	state[pos] = piece
+	passTurn(state)
+}
+
+// WinningLine returns the K cells completed by the piece at pos.
+func (b Board) WinningLine(state []byte, pos int) ([]int, bool) {
+	return b.lineThrough(state[:b.Cells()], state[pos], pos)
+}
+
+// formatMove writes a move in the index form accepted by ParseMove.
+func formatMove(piece byte, pos int) string {
+	return string(piece) + strconv.Itoa(pos)
+}
+
+// Turn returns the lowercase piece whose turn it is from a state string.
+func Turn(state string) byte {
+	if state == "" {
+		return '.'
+	}
+	return strings.ToLower(state[len(state)-1:])[0]
+}
@@ -0,0 +1,22 @@
+package service
+
+import "testing"
+
+func TestBoardLines(t *testing.T) {
+	tests := []struct {
+		board Board
+		want  int
+	}{
+		{ClassicBoard, 8},
+		{Board{Rows: 4, Cols: 4, K: 4}, 10},
+		{Board{Rows: 4, Cols: 4, K: 3}, 24},
+		{Board{Rows: 3, Cols: 5, K: 3}, 20},
+		{Board{Rows: 1, Cols: 5, K: 3}, 3},
+		{Board{Rows: 15, Cols: 15, K: 5}, 572},
+	}
+	for _, tt := range tests {
+		if got := len(tt.board.lines()); got != tt.want {
+			t.Errorf("%dx%d k=%d: got %d lines, want %d", tt.board.Rows, tt.board.Cols, tt.board.K, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"math/rand"
+	"sort"
+	"sync"
+
+	store "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
+)
+
+// Bot is a computer strategy. Given the current game state it returns
+// the move for the side to play in the "x4" format used by MakeMove.
+type Bot interface {
+	Move(gameState store.GameState) (string, error)
+}
+
+// capableBot is implemented by bots that can play more than classic standard games.
+type capableBot interface {
+	Supports(mode, variant string) bool
+}
+
+// BotSupports reports whether bot can play the given mode and variant.
+// Bots that don't say otherwise only play classic boards with standard rules.
+func BotSupports(bot Bot, mode, variant string) bool {
+	if cb, ok := bot.(capableBot); ok {
+		return cb.Supports(mode, variant)
+	}
+	return mode == ModeClassic && variant == VariantStandard
+}
+
+// BotInfo describes a registered bot for listing.
+type BotInfo struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Modes       []string `json:"modes"`
+	Variants    []string `json:"variants"` // variants it plays on classic boards
+}
+
+type registeredBot struct {
+	info BotInfo
+	bot  Bot
+}
+
+// DefaultBot is used when a game is created with isAi but no bot or aiLevel.
+const DefaultBot = "medium"
+
+var (
+	botsMu sync.RWMutex
+	bots   = map[string]registeredBot{}
+)
+
+func init() {
+	RegisterBot("random", "Plays any open square.", randomBot{})
+	RegisterBot("heuristic", "Wins or blocks when it can, otherwise prefers center then corners.", heuristicBot{})
+	RegisterBot("easy", "Shallow minimax that blunders often.", minimaxBot{depth: 2, mistakeRate: 0.4})
+	RegisterBot("medium", "Minimax to depth 4 with the occasional mistake.", minimaxBot{depth: 4, mistakeRate: 0.15})
+	RegisterBot("perfect", "Full alpha-beta minimax. Never loses on 3x3.", minimaxBot{depth: 9, mistakeRate: 0})
+	RegisterBot("mcts", "Monte Carlo tree search with random playouts.", mctsBot{iterations: 2000})
+}
+
+// RegisterBot makes a bot selectable by name. Registering an existing name replaces it.
+func RegisterBot(name, description string, bot Bot) {
+	botsMu.Lock()
+	defer botsMu.Unlock()
+	info := BotInfo{Name: name, Description: description}
+	for _, mode := range Modes {
+		if BotSupports(bot, mode, VariantStandard) {
+			info.Modes = append(info.Modes, mode)
+		}
+	}
+	for _, v := range variants {
+		if BotSupports(bot, ModeClassic, v.Name) {
+			info.Variants = append(info.Variants, v.Name)
+		}
+	}
+	bots[name] = registeredBot{info: info, bot: bot}
+	log.Println("[RegisterBot] Registered bot: ", name)
+}
+
+// GetBot looks up a registered bot by name.
+func GetBot(name string) (Bot, bool) {
+	botsMu.RLock()
+	defer botsMu.RUnlock()
+	entry, ok := bots[name]
+	return entry.bot, ok
+}
+
+// ListBots returns every registered bot sorted by name.
+func ListBots() []BotInfo {
+	botsMu.RLock()
+	defer botsMu.RUnlock()
+	list := make([]BotInfo, 0, len(bots))
+	for _, entry := range bots {
+		list = append(list, entry.info)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// BotNames returns the names of every registered bot, sorted.
+func BotNames() []string {
+	var names []string
+	for _, info := range ListBots() {
+		names = append(names, info.Name)
+	}
+	return names
+}
+
+// botPosition splits a game state into its geometry, the cells and the piece to place.
+func botPosition(gameState store.GameState) (lineBoard, []byte, byte, error) {
+	board, ok := RulesFor(gameState.Config).(lineBoard)
+	if !ok {
+		return nil, nil, 0, errors.New("bot only plays boards won by lines")
+	}
+	if len(gameState.State) != board.Cells()+1 {
+		return board, nil, 0, errors.New("invalid game state")
+	}
+	cells := []byte(gameState.State[:board.Cells()])
+	if len(openSquares(cells)) == 0 {
+		return board, nil, 0, errors.New("no moves available")
+	}
+	return board, cells, Turn(gameState.State), nil
+}
+
+// randomBot plays a uniformly random legal move in any mode and variant.
+type randomBot struct{}
+
+func (randomBot) Supports(string, string) bool { return true }
+
+func (randomBot) Move(gameState store.GameState) (string, error) {
+	moves := RulesFor(gameState.Config).LegalMoves([]byte(gameState.State))
+	if len(moves) == 0 {
+		return "", errors.New("no moves available")
+	}
+	pieces := VariantFor(gameState.Config).Pieces(Turn(gameState.State))
+	return formatMove(pieces[rand.Intn(len(pieces))], moves[rand.Intn(len(moves))]), nil
+}
+
+// heuristicBot wins if possible, otherwise blocks, otherwise plays the
+// square that sits on the most lines the opponent has not touched
+// (center, then corners, then edges on the classic board).
+type heuristicBot struct{}
+
+func (heuristicBot) Move(gameState store.GameState) (string, error) {
+	board, cells, me, err := botPosition(gameState)
+	if err != nil {
+		return "", err
+	}
+	if pos, ok := completingSquare(board, cells, me); ok {
+		return formatMove(me, pos), nil
+	}
+	opponent := otherPiece(me)
+	if pos, ok := completingSquare(board, cells, opponent); ok {
+		return formatMove(me, pos), nil
+	}
+	best := -1
+	var bestSquares []int
+	for _, pos := range openSquares(cells) {
+		score := 0
+		for _, line := range board.geometry().linesThrough[pos] {
+			if !lineHas(cells, line, opponent) {
+				score++
+			}
+		}
+		if score > best {
+			best, bestSquares = score, []int{pos}
+		} else if score == best {
+			bestSquares = append(bestSquares, pos)
+		}
+	}
+	return formatMove(me, bestSquares[rand.Intn(len(bestSquares))]), nil
+}
+
+// completingSquare finds an open square that completes a line for player.
+func completingSquare(board lineBoard, cells []byte, player byte) (int, bool) {
+	for _, line := range board.geometry().lines {
+		count, open := 0, -1
+		for _, pos := range line {
+			switch cells[pos] {
+			case player:
+				count++
+			case '.':
+				open = pos
+			}
+		}
+		if count == len(line)-1 && open != -1 {
+			return open, true
+		}
+	}
+	return -1, false
+}
+
+func lineHas(cells []byte, line []int, piece byte) bool {
+	for _, pos := range line {
+		if cells[pos] == piece {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"errors"
+	"log"
+
+	store "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
+)
+
+// ErrTimeout is returned for a move made after the mover's clock ran out.
+var ErrTimeout = errors.New("time has run out")
+
+// Clock is the time left on each side in seconds. A side's time only runs
+// while it is their turn, starting once both seats are filled.
+type Clock struct {
+	X        int64  `json:"x"`
+	O        int64  `json:"o"`
+	Turn     string `json:"turn"`               // side whose clock is running
+	Deadline int64  `json:"deadline,omitempty"` // unix seconds the side to move runs out; 0 once the game is over
+	Now      int64  `json:"now"`                // server time the clock was read at
+}
+
+// HasTimeControl reports whether the game was created with a clock.
+func HasTimeControl(config store.GameConfig) bool {
+	return config.MoveTime > 0 || config.BaseTime > 0
+}
+
+// ValidateTimeControl checks the time control chosen at creation.
+func ValidateTimeControl(config store.GameConfig) error {
+	if config.MoveTime < 0 || config.BaseTime < 0 || config.Increment < 0 {
+		return errors.New("times cannot be negative")
+	}
+	if config.MoveTime > 0 && config.BaseTime > 0 {
+		return errors.New("choose a time per move or a time per game, not both")
+	}
+	if config.Increment > 0 && config.BaseTime == 0 {
+		return errors.New("an increment needs a time per game")
+	}
+	return nil
+}
+
+// ClockFor works out the clocks at now from a game's rows, oldest first,
+// using each row's LastUpdate. A side is charged from the row that passed
+// the turn to them until the row that passed it back. ok is false when the
+// game has no time control or has not started.
+func ClockFor(rows []store.GameState, now int64) (clock Clock, ok bool) {
+	if len(rows) == 0 || !HasTimeControl(rows[0].Config) {
+		return Clock{}, false
+	}
+	config := rows[0].Config
+	remaining := map[byte]int64{'x': int64(config.BaseTime), 'o': int64(config.BaseTime)}
+	if config.MoveTime > 0 {
+		remaining['x'], remaining['o'] = int64(config.MoveTime), int64(config.MoveTime)
+	}
+	started := false
+	var turnStart int64
+	for i, row := range rows {
+		if !started {
+			if row.PlayerX != "" && row.PlayerO != "" {
+				started = true
+				turnStart = row.LastUpdate
+			}
+			continue
+		}
+		mover := Turn(rows[i-1].State)
+		if mover == Turn(row.State) {
+			continue
+		}
+		if config.BaseTime > 0 {
+			remaining[mover] += int64(config.Increment) - (row.LastUpdate - turnStart)
+		}
+		turnStart = row.LastUpdate
+	}
+	if !started {
+		return Clock{}, false
+	}
+	last := rows[len(rows)-1]
+	side := Turn(last.State)
+	clock = Clock{Turn: string(side), Now: now}
+	if last.Status == StatusActive {
+		clock.Deadline = turnStart + remaining[side]
+		remaining[side] = max(clock.Deadline-now, 0)
+	} else {
+		//stopped when the game ended
+		remaining[side] = max(turnStart+remaining[side]-last.LastUpdate, 0)
+	}
+	clock.X, clock.O = remaining['x'], remaining['o']
+	return clock, true
+}
+
+// GetClock reads a game's clock now. ok is false when it has none running.
+func GetClock(gameID string, now int64) (Clock, bool, error) {
+	rows, err := store.GetGameHistory(gameID)
+	if err != nil {
+		log.Println("[GetClock] Failed to get game history: ", err)
+		return Clock{}, false, err
+	}
+	clock, ok := ClockFor(rows, now)
+	return clock, ok, nil
+}
+
+// TimeOut ends the game if the side to move has run out of time at now and
+// returns the final state. flagged is false if the game carries on.
+func TimeOut(gameID string, now int64) (gameState store.GameState, flagged bool, err error) {
+	unlock := lockGame(gameID)
+	defer unlock()
+	return timeOut(gameID, now)
+}
+
+// timeOut is TimeOut for a caller that holds the game's lock.
+func timeOut(gameID string, now int64) (gameState store.GameState, flagged bool, err error) {
+	rows, err := store.GetGameHistory(gameID)
+	if err != nil {
+		log.Println("[TimeOut] Failed to get game history: ", err)
+		return store.GameState{}, false, err
+	}
+	clock, ok := ClockFor(rows, now)
+	if !ok || clock.Deadline == 0 || now <= clock.Deadline {
+		return store.GameState{}, false, nil
+	}
+	gameState = rows[len(rows)-1]
+	winner := gameState.PlayerO
+	if clock.Turn == "o" {
+		winner = gameState.PlayerX
+	}
+	log.Println("[TimeOut] Side ", clock.Turn, " flagged in game ", gameID)
+	gameState, err = endGame(gameID, gameState, EndStatus(winner, ReasonTimeout))
+	if err != nil {
+		return store.GameState{}, false, err
+	}
+	return gameState, true, nil
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"testing"
+
+	store "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
+)
+
+// clockRows is a timed game: seated at 100, x moves at 110, o at 130.
+func clockRows(config store.GameConfig, status string) []store.GameState {
+	return []store.GameState{
+		{State: ".........X", Status: StatusActive, LastUpdate: 0, Config: config},
+		{State: ".........X", Status: StatusActive, LastUpdate: 100, PlayerX: "px", PlayerO: "po", Config: config},
+		{State: "x........o", Status: StatusActive, LastUpdate: 110, PlayerX: "px", PlayerO: "po", Config: config},
+		{State: "x...o....x", Status: status, LastUpdate: 130, PlayerX: "px", PlayerO: "po", Config: config},
+	}
+}
+
+func TestClockFor(t *testing.T) {
+	tests := []struct {
+		name   string
+		rows   []store.GameState
+		now    int64
+		want   Clock
+		wantOk bool
+	}{
+		{
+			name:   "increment added after each move",
+			rows:   clockRows(store.GameConfig{BaseTime: 60, Increment: 5}, StatusActive),
+			now:    140,
+			want:   Clock{X: 45, O: 45, Turn: "x", Deadline: 185, Now: 140},
+			wantOk: true,
+		},
+		{
+			name:   "no increment",
+			rows:   clockRows(store.GameConfig{BaseTime: 60}, StatusActive),
+			now:    140,
+			want:   Clock{X: 40, O: 40, Turn: "x", Deadline: 180, Now: 140},
+			wantOk: true,
+		},
+		{
+			name:   "flag fell",
+			rows:   clockRows(store.GameConfig{BaseTime: 60, Increment: 5}, StatusActive),
+			now:    200,
+			want:   Clock{X: 0, O: 45, Turn: "x", Deadline: 185, Now: 200},
+			wantOk: true,
+		},
+		{
+			name:   "per move time resets each turn",
+			rows:   clockRows(store.GameConfig{MoveTime: 30}, StatusActive),
+			now:    140,
+			want:   Clock{X: 20, O: 30, Turn: "x", Deadline: 160, Now: 140},
+			wantOk: true,
+		},
+		{
+			name:   "stopped when the game ended",
+			rows:   clockRows(store.GameConfig{BaseTime: 60, Increment: 5}, "po:resigned"),
+			now:    500,
+			want:   Clock{X: 55, O: 45, Turn: "x", Now: 500},
+			wantOk: true,
+		},
+		{
+			name: "not started",
+			rows: clockRows(store.GameConfig{BaseTime: 60}, StatusActive)[:1],
+			now:  140,
+		},
+		{
+			name: "no time control",
+			rows: clockRows(store.GameConfig{}, StatusActive),
+			now:  140,
+		},
+	}
+	for _, tt := range tests {
+		got, ok := ClockFor(tt.rows, tt.now)
+		if ok != tt.wantOk || got != tt.want {
+			t.Errorf("%s: got %+v, %v, want %+v, %v", tt.name, got, ok, tt.want, tt.wantOk)
+		}
+	}
+}
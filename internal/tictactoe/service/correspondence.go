@@ -0,0 +1,224 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	store "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
+)
+
+// MaxMoveHours is the longest per-move deadline a correspondence game may have.
+const MaxMoveHours = 30 * 24
+
+// Notice kinds.
+const (
+	NoticeReminder = "reminder" // the player's deadline is approaching
+	NoticeForfeit  = "forfeit"  // the side to move missed their deadline and lost
+)
+
+// Notice is a message about a correspondence game for one player.
+type Notice struct {
+	Kind     string `json:"kind"`
+	GameID   string `json:"gameId"`
+	PlayerID string `json:"playerId"`
+	Deadline int64  `json:"deadline"` // unix seconds
+}
+
+// Notifier delivers notices to players, e.g. by email or push.
+type Notifier interface {
+	Notify(notice Notice) error
+}
+
+// LogNotifier is a Notifier for local use. It logs every notice and, when
+// Path is set, also appends it to that file as a JSON line.
+type LogNotifier struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func (n *LogNotifier) Notify(notice Notice) error {
+	log.Printf("[LogNotifier] %s for player %s in game %s, deadline %s", notice.Kind, notice.PlayerID, notice.GameID, time.Unix(notice.Deadline, 0).Format(time.RFC3339))
+	if n.Path == "" {
+		return nil
+	}
+	line, err := json.Marshal(notice)
+	if err != nil {
+		return err
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	f, err := os.OpenFile(n.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// ValidateMoveHours checks a correspondence deadline chosen at creation.
+func ValidateMoveHours(hours int) error {
+	if hours < 1 || hours > MaxMoveHours {
+		return errors.New("move deadline must be between 1 and " + strconv.Itoa(MaxMoveHours) + " hours")
+	}
+	return nil
+}
+
+// correspondenceGames is the set of unfinished correspondence games, so the
+// scheduler reads only those instead of every stored game. It is loaded
+// from disk on first use and kept current by NewGame and the scheduler.
+var correspondenceGames struct {
+	sync.Mutex
+	ids    map[string]bool
+	loaded bool
+}
+
+// loadCorrespondenceGames fills the set from the stored games. The caller
+// holds its lock.
+func loadCorrespondenceGames() {
+	if correspondenceGames.loaded {
+		return
+	}
+	correspondenceGames.ids = make(map[string]bool)
+	correspondenceGames.loaded = true
+	ids, err := store.ListGames()
+	if err != nil {
+		log.Println("[loadCorrespondenceGames] Failed to list games: ", err)
+		return
+	}
+	for _, gameID := range ids {
+		gameState, err := store.GetGameState(gameID)
+		if err == nil && gameState.Config.Correspondence && gameState.Status == StatusActive {
+			correspondenceGames.ids[gameID] = true
+		}
+	}
+	log.Println("[loadCorrespondenceGames] Tracking ", len(correspondenceGames.ids), " correspondence games")
+}
+
+// trackCorrespondence adds a new correspondence game to the set.
+func trackCorrespondence(gameID string) {
+	correspondenceGames.Lock()
+	defer correspondenceGames.Unlock()
+	loadCorrespondenceGames()
+	correspondenceGames.ids[gameID] = true
+}
+
+// untrackCorrespondence drops a finished game from the set.
+func untrackCorrespondence(gameID string) {
+	correspondenceGames.Lock()
+	defer correspondenceGames.Unlock()
+	delete(correspondenceGames.ids, gameID)
+}
+
+// correspondenceIDs lists the tracked games.
+func correspondenceIDs() []string {
+	correspondenceGames.Lock()
+	defer correspondenceGames.Unlock()
+	loadCorrespondenceGames()
+	ids := make([]string, 0, len(correspondenceGames.ids))
+	for gameID := range correspondenceGames.ids {
+		ids = append(ids, gameID)
+	}
+	return ids
+}
+
+// Scheduler periodically checks the unfinished correspondence games,
+// forfeits those whose side to move is past its deadline and reminds
+// players once a quarter of their move time is left.
+type Scheduler struct {
+	Interval time.Duration
+	Notifier Notifier
+	// OnForfeit is called with the final state of each game the scheduler ends.
+	OnForfeit func(gameID string, gameState store.GameState)
+
+	reminded map[string]int64 // game ID to the deadline already reminded about
+}
+
+// NewScheduler returns a Scheduler that scans every minute.
+func NewScheduler(notifier Notifier) *Scheduler {
+	return &Scheduler{Interval: time.Minute, Notifier: notifier, reminded: make(map[string]int64)}
+}
+
+// Start scans in the background until the returned stop function is called.
+func (s *Scheduler) Start() (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(s.Interval)
+		defer ticker.Stop()
+		for {
+			s.Scan(time.Now())
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// Scan checks every unfinished correspondence game once.
+func (s *Scheduler) Scan(now time.Time) {
+	for _, gameID := range correspondenceIDs() {
+		s.check(gameID, now.Unix())
+	}
+}
+
+func (s *Scheduler) check(gameID string, now int64) {
+	rows, err := store.GetGameHistory(gameID)
+	if err != nil {
+		log.Println("[Scheduler] Failed to get game history: ", err)
+		return
+	}
+	if len(rows) == 0 || !rows[0].Config.Correspondence || rows[len(rows)-1].Status != StatusActive {
+		untrackCorrespondence(gameID)
+		delete(s.reminded, gameID)
+		return
+	}
+	clock, ok := ClockFor(rows, now)
+	if !ok || clock.Deadline == 0 {
+		delete(s.reminded, gameID)
+		return
+	}
+	last := rows[len(rows)-1]
+	toMove := last.PlayerO
+	if clock.Turn == "x" {
+		toMove = last.PlayerX
+	}
+	if now > clock.Deadline {
+		gameState, flagged, err := TimeOut(gameID, now)
+		if err != nil || !flagged {
+			return
+		}
+		log.Println("[Scheduler] Forfeited overdue game: ", gameID)
+		untrackCorrespondence(gameID)
+		delete(s.reminded, gameID)
+		for _, player := range []string{last.PlayerX, last.PlayerO} {
+			s.notify(Notice{Kind: NoticeForfeit, GameID: gameID, PlayerID: player, Deadline: clock.Deadline})
+		}
+		if s.OnForfeit != nil {
+			s.OnForfeit(gameID, gameState)
+		}
+		return
+	}
+	if clock.Deadline-now > int64(rows[0].Config.MoveTime)/4 || s.reminded[gameID] == clock.Deadline {
+		return
+	}
+	s.reminded[gameID] = clock.Deadline
+	s.notify(Notice{Kind: NoticeReminder, GameID: gameID, PlayerID: toMove, Deadline: clock.Deadline})
+}
+
+// notify sends a notice to anyone but the computer.
+func (s *Scheduler) notify(notice Notice) {
+	if notice.PlayerID == AIPlayerID || s.Notifier == nil {
+		return
+	}
+	if err := s.Notifier.Notify(notice); err != nil {
+		log.Println("[Scheduler] Failed to send notice: ", err)
+	}
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"log"
+	"math/rand"
+
+	store "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
+)
+
+// largeBoardDepth caps the search depth on anything bigger than 3x3,
+// where searching every open square to full depth is far too slow.
+const largeBoardDepth = 3
+
+// winScore is far above anything evaluate can return.
+const winScore = 1 << 40
+
+// minimaxBot searches the game tree with alpha-beta pruning.
+// depth limits the search and mistakeRate is the chance of ignoring
+// the search and playing a random open square instead.
+type minimaxBot struct {
+	depth       int
+	mistakeRate float64
+}
+
+func (minimaxBot) Supports(mode, _ string) bool { return mode == ModeClassic || mode == ModeQubic }
+
+// lineBoard is a mode won by owning any one of a fixed set of lines, which
+// is all the search needs to know about it.
+type lineBoard interface {
+	Rules
+	geometry() *boardGeometry
+	// candidateSquares lists the open squares worth searching, best first.
+	candidateSquares(cells []byte) []int
+}
+
+func (b minimaxBot) Move(gameState store.GameState) (string, error) {
+	board, cells, me, err := botPosition(gameState)
+	if err != nil {
+		return "", err
+	}
+	variant := VariantFor(gameState.Config)
+	pieces := variant.Pieces(me)
+	open := openSquares(cells)
+	if rand.Float64() < b.mistakeRate {
+		log.Println("[minimaxBot] Playing a deliberate mistake")
+		return formatMove(pieces[rand.Intn(len(pieces))], open[rand.Intn(len(open))]), nil
+	}
+	depth := b.depth
+	if board.Cells() > 9 && depth > largeBoardDepth {
+		depth = largeBoardDepth
+	}
+	best := -winScore * 2
+	var bestMoves []string
+	for _, pos := range board.candidateSquares(cells) {
+		for _, piece := range pieces {
+			cells[pos] = piece
+			var score int
+			if _, done := board.geometry().lineThrough(cells, piece, pos); done {
+				score = lineScore(variant, me, len(open))
+			} else {
+				// alpha is one below best so equal scores are exact and ties can be collected
+				score = -negamax(board, variant, cells, otherPiece(me), depth-1, -winScore*2, -(best - 1))
+			}
+			cells[pos] = '.'
+			if score > best {
+				best = score
+				bestMoves = []string{formatMove(piece, pos)}
+			} else if score == best {
+				bestMoves = append(bestMoves, formatMove(piece, pos))
+			}
+		}
+	}
+	log.Println("[minimaxBot] Depth: ", depth, " Score: ", best, " Candidates: ", bestMoves)
+	return bestMoves[rand.Intn(len(bestMoves))], nil
+}
+
+// negamax scores the position from the point of view of me, the side to move,
+// using alpha-beta pruning. Faster wins and slower losses score higher.
+func negamax(board lineBoard, variant Variant, cells []byte, me byte, depth, alpha, beta int) int {
+	open := board.candidateSquares(cells)
+	if len(open) == 0 {
+		return 0
+	}
+	if depth <= 0 {
+		if variant.Name != VariantStandard {
+			return 0 // line counting only means something when pieces belong to sides
+		}
+		return evaluate(board, cells, me, otherPiece(me))
+	}
+	for _, pos := range open {
+		for _, piece := range variant.Pieces(me) {
+			cells[pos] = piece
+			var score int
+			if _, done := board.geometry().lineThrough(cells, piece, pos); done {
+				score = lineScore(variant, me, len(open))
+			} else {
+				score = -negamax(board, variant, cells, otherPiece(me), depth-1, -beta, -alpha)
+			}
+			cells[pos] = '.'
+			if score > alpha {
+				alpha = score
+			}
+			if alpha >= beta {
+				return alpha
+			}
+		}
+	}
+	return alpha
+}
+
+// lineScore scores a line just completed by mover from mover's point of view:
+// a win, or a loss when the variant punishes completing lines. Sooner is
+// more extreme so wins are taken quickly and losses put off.
+func lineScore(variant Variant, mover byte, open int) int {
+	if variant.Winner(mover) == mover {
+		return winScore + open
+	}
+	return -(winScore + open)
+}
+
+// evaluate is the static score used when the search runs out of depth:
+// lines still open for me count for, lines open for the opponent count
+// against, and lines closer to completion count much more.
+func evaluate(board lineBoard, cells []byte, me, opponent byte) int {
+	score := 0
+	for _, line := range board.geometry().lines {
+		mine, theirs := 0, 0
+		for _, pos := range line {
+			switch cells[pos] {
+			case me:
+				mine++
+			case opponent:
+				theirs++
+			}
+		}
+		if theirs == 0 && mine > 0 {
+			score += lineWeight(mine)
+		}
+		if mine == 0 && theirs > 0 {
+			score -= lineWeight(theirs)
+		}
+	}
+	return score
+}
+
+func lineWeight(count int) int {
+	if count > 10 {
+		count = 10
+	}
+	return 1 << (2 * (count - 1))
+}
+
+// candidateSquares lists the open squares worth searching. On the classic
+// board that is every open square; on larger boards only squares next to
+// a piece already played, or the center on an empty board.
+func (board Board) candidateSquares(cells []byte) []int {
+	open := openSquares(cells)
+	if board.Cells() <= 9 {
+		return open
+	}
+	if len(open) == len(cells) {
+		return []int{(board.Rows/2)*board.Cols + board.Cols/2}
+	}
+	var near []int
+	for _, pos := range open {
+		row, col := pos/board.Cols, pos%board.Cols
+		found := false
+		for dr := -1; dr <= 1 && !found; dr++ {
+			for dc := -1; dc <= 1 && !found; dc++ {
+				r, c := row+dr, col+dc
+				if r >= 0 && r < board.Rows && c >= 0 && c < board.Cols && cells[r*board.Cols+c] != '.' {
+					found = true
+				}
+			}
+		}
+		if found {
+			near = append(near, pos)
+		}
+	}
+	if len(near) == 0 {
+		return open
+	}
+	return near
+}
+
+func openSquares(cells []byte) []int {
+	var open []int
+	for pos, cell := range cells {
+		if cell == '.' {
+			open = append(open, pos)
+		}
+	}
+	return open
+}
+
+func otherPiece(piece byte) byte {
+	if piece == 'x' {
+		return 'o'
+	}
+	return 'x'
+}
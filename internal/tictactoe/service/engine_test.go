@@ -0,0 +1,36 @@
+package service
+
+import "testing"
+
+func TestNegamax(t *testing.T) {
+	tests := []struct {
+		name  string
+		state string // cells followed by the side to move
+		want  int    // 1 the side to move wins, -1 it loses, 0 a draw
+	}{
+		{"empty board", ".........x", 0},
+		{"win on the spot", "xx.oo....x", 1},
+		{"must block", "xx..o....o", 0},
+		{"corner against adjacent edge", "xo.......x", 1},
+		{"center against corner", "x...o....x", 0},
+		{"double threat", "xx.xo...oo", -1},
+	}
+	for _, tt := range tests {
+		cells := []byte(tt.state[:9])
+		me := tt.state[9]
+		score := negamax(ClassicBoard, variants[0], cells, me, 9, -winScore*2, winScore*2)
+		got := 0
+		switch {
+		case score >= winScore:
+			got = 1
+		case score <= -winScore:
+			got = -1
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %d (score %d), want %d", tt.name, got, score, tt.want)
+		}
+		if string(cells) != tt.state[:9] {
+			t.Errorf("%s: search left the board changed: %s", tt.name, cells)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	store "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
+)
+
+// ExternalBotTimeout is how long an external bot may take to answer one move.
+const ExternalBotTimeout = 5 * time.Second
+
+// externalBot runs a local executable for every move.
+//
+// Protocol: the server starts the program, writes the 10 character state
+// string (e.g. "x...o...." + turn) followed by a newline to its stdin and
+// reads a single line from its stdout containing the move (e.g. "o4").
+// On any board other than 3x3 the line is prefixed with the geometry as
+// "<rows> <cols> <k> <state>".
+// The process is killed once the line is read. Exiting without answering,
+// answering late or answering with an illegal move forfeits the game.
+type externalBot struct {
+	path    string
+	timeout time.Duration
+}
+
+type externalReply struct {
+	line string
+	err  error
+}
+
+func (b externalBot) Move(gameState store.GameState) (string, error) {
+	cmd := exec.Command(b.path)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return "", err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second // don't hang on children that inherited the pipes
+	if err := cmd.Start(); err != nil {
+		log.Println("[externalBot] Failed to start: ", b.path, " ", err)
+		return "", err
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+
+	replies := make(chan externalReply, 1)
+	go func() {
+		line, err := bufio.NewReader(stdout).ReadString('\n')
+		if err == io.EOF && line != "" {
+			err = nil // last line without trailing newline
+		}
+		replies <- externalReply{line: strings.TrimSpace(line), err: err}
+	}()
+	line := gameState.State
+	if board := BoardFor(gameState.Config); board != ClassicBoard {
+		line = fmt.Sprintf("%d %d %d %s", board.Rows, board.Cols, board.K, gameState.State)
+	}
+	if _, err := io.WriteString(stdin, line+"\n"); err != nil {
+		// the bot may have answered without reading; its reply decides
+		log.Println("[externalBot] Failed to write state: ", b.path, " ", err)
+	}
+	_ = stdin.Close()
+
+	select {
+	case reply := <-replies:
+		if reply.err != nil {
+			log.Println("[externalBot] Bot exited without a move: ", b.path, " stderr: ", stderr.String())
+			return "", fmt.Errorf("external bot crashed: %w", reply.err)
+		}
+		log.Println("[externalBot] Bot answered: ", reply.line)
+		return reply.line, nil
+	case <-time.After(b.timeout):
+		log.Println("[externalBot] Bot timed out: ", b.path)
+		return "", errors.New("external bot timed out")
+	}
+}
+
+// LoadExternalBots registers every executable file in dir as a bot named
+// "ext-<file name without extension>". A missing directory is not an error.
+func LoadExternalBots(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Println("[LoadExternalBots] No external bot directory: ", dir)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+			continue
+		}
+		path, err := filepath.Abs(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		name := "ext-" + strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		RegisterBot(name, "External program "+entry.Name()+".", externalBot{path: path, timeout: ExternalBotTimeout})
+	}
+	return nil
+}
@@ -3,104 +3,152 @@ package service
 import (
 	"errors"
 	"log"
+	"time"
 
 	store "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
 )
 
-func MakeMove(gameID, move string) (string, error) {
-	//prepare move
-	turn := move[0]
-	position := int(move[1] - '0')
-	log.Println("[MakeMove] Turn: ", turn, " Position: ", position)
+// NewGame creates a game with config and the initial state of its mode and
+// returns its ID.
+func NewGame(config store.GameConfig) (string, error) {
+	gameID, err := store.NewGame(config, RulesFor(config).InitialState())
+	if err != nil {
+		log.Println("[NewGame] Failed to create game: ", err)
+		return "", err
+	}
+	if config.Correspondence {
+		trackCorrespondence(gameID)
+	}
+	return gameID, nil
+}
+
+// ErrNotYourTurn is returned for a move by anyone but the player seated on the side to move.
+var ErrNotYourTurn = errors.New("not your turn")
+
+// MakeMove plays move for playerID and returns the stored state afterwards.
+func MakeMove(gameID, playerID, move string) (store.GameState, error) {
+	unlock := lockGame(gameID)
+	defer unlock()
+	return makeMove(gameID, playerID, move)
+}
+
+// makeMove is MakeMove for a caller that holds the game's lock.
+func makeMove(gameID, playerID, move string) (store.GameState, error) {
 	gameState, err := store.GetGameState(gameID)
 	if err != nil {
 		log.Println("[MakeMove] Failed to get game state: ", err)
-		return "", err
+		return store.GameState{}, err
 	}
-	//validate move
-	if gameState.State[position] != '.' {
+	//prepare move
+	rules := RulesFor(gameState.Config)
+	variant := VariantFor(gameState.Config)
+	piece, position, err := rules.ParseMove(move)
+	if err != nil {
 		log.Println("[MakeMove] Invalid move: ", move)
-		return "", errors.New("invalid move")
+		return store.GameState{}, err
+	}
+	turn := Turn(gameState.State)
+	log.Println("[MakeMove] Turn: ", turn, " Piece: ", piece, " Position: ", position)
+	//validate move
+	if gameState.Status != StatusActive {
+		log.Println("[MakeMove] Game is over: ", gameState.Status)
+		return store.GameState{}, ErrGameOver
+	}
+	if HasTimeControl(gameState.Config) {
+		if _, flagged, err := timeOut(gameID, time.Now().Unix()); err != nil {
+			return store.GameState{}, err
+		} else if flagged {
+			log.Println("[MakeMove] Move after flag fall: ", move)
+			return store.GameState{}, ErrTimeout
+		}
+	}
+	mover := gameState.PlayerO
+	if turn == 'x' {
+		mover = gameState.PlayerX
+	}
+	if mover == "" || mover != playerID {
+		log.Println("[MakeMove] Not the turn of player: ", playerID)
+		return store.GameState{}, ErrNotYourTurn
+	}
+	if err := checkMove(rules, variant, gameState.State, piece, position); err != nil {
+		log.Println("[MakeMove] Invalid move: ", move, " ", err)
+		return store.GameState{}, err
 	}
 	//alter game state
-	gameState = alterGameState(gameState, byte(turn), position)
+	gameState = alterGameState(gameState, piece, position)
 	err = store.UpdateGameState(gameID, gameState)
 	if err != nil {
 		log.Println("[MakeMove] Failed to update game state: ", err)
-		return "", err
+		return store.GameState{}, err
 	}
 	//check if game is over
-	if gameWon(gameState.State) {
-		if gameState.State[9] == 'x' {
-			gameState.Status = gameState.PlayerX
+	if gameWon(rules, gameState.State, position) {
+		if variant.Winner(turn) == 'x' {
+			gameState.Status = EndStatus(gameState.PlayerX, ReasonLine)
 		} else {
-			gameState.Status = gameState.PlayerO
+			gameState.Status = EndStatus(gameState.PlayerO, ReasonLine)
 		}
 		err = store.UpdateGameState(gameID, gameState)
 		if err != nil {
 			log.Println("[MakeMove] Failed to update game state: ", err)
-			return "", err
+			return store.GameState{}, err
 		}
-	} else if gameTied(gameState.State) {
-		gameState.Status = "tied"
+	} else if gameTied(rules, gameState.State) {
+		gameState.Status = EndStatus(StatusTied, ReasonBoardFull)
 		err = store.UpdateGameState(gameID, gameState)
 		if err != nil {
 			log.Println("[MakeMove] Failed to update game state: ", err)
-			return "", err
+			return store.GameState{}, err
 		}
 	}
 	//ensure we are senidng back truth
 	gameState, err = store.GetGameState(gameID)
 	if err != nil {
 		log.Println("[MakeMove] Failed to get game state: ", err)
-		return "", err
+		return store.GameState{}, err
+	}
+	if gameState.Status != StatusActive {
+		rateGame(gameState)
+	}
+	return gameState, nil
+}
+
+// ValidateMove checks that move is well formed, uses a piece the side to
+// play may place and is legal under the game's rules and variant.
+func ValidateMove(gameState store.GameState, move string) error {
+	rules := RulesFor(gameState.Config)
+	piece, position, err := rules.ParseMove(move)
+	if err != nil {
+		return err
+	}
+	if err := checkMove(rules, VariantFor(gameState.Config), gameState.State, piece, position); err != nil {
+		return errors.New(err.Error() + ": " + move)
 	}
-	return gameState.State, nil
+	return nil
 }
 
-func alterGameState(gameState store.GameState, turn byte, position int) store.GameState {
+func alterGameState(gameState store.GameState, piece byte, position int) store.GameState {
 	log.Println("[alterGameState] Altering game state: ", gameState)
 	gameBytes := []byte(gameState.State)
-	gameBytes[position] = turn
-	var nextTurn byte
-	if turn == 'x' {
-		nextTurn = 'o'
-	} else {
-		nextTurn = 'x'
-	}
-	gameBytes[9] = nextTurn
+	RulesFor(gameState.Config).Place(gameBytes, piece, position)
 	gameState.State = string(gameBytes)
-	gameState.Status = "active"
+	gameState.Status = StatusActive
 	log.Println("[alterGameState] Game state altered: ", gameState)
 	return gameState
 }
 
-var wins = [8][3]int{
-	{0, 1, 2}, // row 1
-	{3, 4, 5}, // row 2
-	{6, 7, 8}, // row 3
-	{0, 3, 6}, // col 1
-	{1, 4, 7}, // col 2
-	{2, 5, 8}, // col 3
-	{0, 4, 8}, // diagonal
-	{2, 4, 6}, // diagonal
-}
-
-func gameWon(gameState string) bool {
-	for _, win := range wins {
-		if gameState[win[0]] != '.' && gameState[win[0]] == gameState[win[1]] && gameState[win[0]] == gameState[win[2]] {
-			log.Println("[gameWon] Game won by: ", gameState[9])
-			return true
-		}
+// gameWon reports whether the piece just placed at position completed a line.
+func gameWon(rules Rules, gameState string, position int) bool {
+	if line, ok := rules.WinningLine([]byte(gameState), position); ok {
+		log.Println("[gameWon] Game won by: ", string(gameState[position]), " line: ", line)
+		return true
 	}
 	log.Println("[gameWon] Game not won")
 	return false
 }
-func gameTied(gameState string) bool {
-	for _, square := range gameState {
-		if square == '.' {
-			return false
-		}
+func gameTied(rules Rules, gameState string) bool {
+	if len(rules.LegalMoves([]byte(gameState))) > 0 {
+		return false
 	}
 	log.Println("[gameTied] Game tied")
 	return true
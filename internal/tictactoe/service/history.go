@@ -0,0 +1,110 @@
+package service
+
+import (
+	store "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
+)
+
+// HistoryMove is one move recovered from a game's stored rows.
+type HistoryMove struct {
+	Number    int    `json:"number"` // 1 for the first move
+	Move      string `json:"move"`   // piece and cell, e.g. "x4"
+	Position  int    `json:"position"`
+	Side      string `json:"side"` // side that moved; differs from the piece in wild
+	PlayerID  string `json:"playerId"`
+	Timestamp int64  `json:"timestamp"`  // unix seconds the move was stored
+	GameState string `json:"game_state"` // position after the move
+}
+
+// ReplayStep is the position after Step moves; step 0 is the empty board.
+type ReplayStep struct {
+	Step      int          `json:"step"`
+	GameState string       `json:"game_state"`
+	Move      *HistoryMove `json:"move,omitempty"` // move that led here
+	Result    GameResult   `json:"result"`
+}
+
+// History lists the moves played in a game, oldest first.
+func History(rows []store.GameState) []HistoryMove {
+	var moves []HistoryMove
+	for _, step := range Replay(rows) {
+		if step.Move != nil {
+			moves = append(moves, *step.Move)
+		}
+	}
+	return moves
+}
+
+// Replay turns a game's rows, oldest first, into one step per position.
+// Rows that only seat players or record the result are folded into the
+// position they belong to, so the last step carries the final status.
+// Moves undone by a takeback are left out.
+func Replay(rows []store.GameState) []ReplayStep {
+	if len(rows) == 0 {
+		return nil
+	}
+	rules := RulesFor(rows[0].Config)
+	positions := []store.GameState{rows[0]}
+	moves := []*HistoryMove{nil}
+	for _, row := range rows[1:] {
+		last := positions[len(positions)-1]
+		pos, ok := placedCell(rules, last.State, row.State)
+		if !ok {
+			//a takeback appends an earlier position again; drop the moves it undid
+			if step := earlierPosition(positions, row.State); step >= 0 {
+				positions, moves = positions[:step+1], moves[:step+1]
+			}
+			positions[len(positions)-1] = row
+			continue
+		}
+		side := Turn(last.State)
+		player := row.PlayerO
+		if side == 'x' {
+			player = row.PlayerX
+		}
+		moves = append(moves, &HistoryMove{
+			Number:    len(positions),
+			Move:      formatMove(row.State[pos], pos),
+			Position:  pos,
+			Side:      string(side),
+			PlayerID:  player,
+			Timestamp: row.LastUpdate,
+			GameState: row.State,
+		})
+		positions = append(positions, row)
+	}
+	steps := make([]ReplayStep, len(positions))
+	for i, gameState := range positions {
+		steps[i] = ReplayStep{Step: i, GameState: gameState.State, Move: moves[i], Result: Result(gameState)}
+	}
+	return steps
+}
+
+// placedCell finds the single cell filled between two states. It reports
+// false when the cells are unchanged or changed in any other way.
+func placedCell(rules Rules, before, after string) (int, bool) {
+	n := rules.Cells()
+	if len(before) < n || len(after) < n {
+		return 0, false
+	}
+	placed := -1
+	for pos := 0; pos < n; pos++ {
+		if before[pos] == after[pos] {
+			continue
+		}
+		if before[pos] != '.' || placed != -1 {
+			return 0, false
+		}
+		placed = pos
+	}
+	return placed, placed != -1
+}
+
+// earlierPosition returns the step before the last whose state is state, or -1.
+func earlierPosition(positions []store.GameState, state string) int {
+	for step := len(positions) - 2; step >= 0; step-- {
+		if positions[step].State == state {
+			return step
+		}
+	}
+	return -1
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	store "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
+)
+
+// historyRows builds a classic game's rows from its states; the first
+// row is the empty board before anyone sat down.
+func historyRows(states ...string) []store.GameState {
+	config := store.GameConfig{Mode: ModeClassic, Variant: VariantStandard, Rows: 3, Cols: 3, K: 3}
+	rows := []store.GameState{{State: ".........X", Status: StatusActive, Config: config}}
+	for i, state := range states {
+		rows = append(rows, store.GameState{State: state, Status: StatusActive, PlayerX: "px", PlayerO: "po", LastUpdate: int64(i + 1), Config: config})
+	}
+	return rows
+}
+
+func TestReplay(t *testing.T) {
+	tests := []struct {
+		name      string
+		rows      []store.GameState
+		wantMoves []string
+		wantFinal string
+	}{
+		{
+			name:      "no takeback",
+			rows:      historyRows(".........X", "x........o", "x...o....x"),
+			wantMoves: []string{"x0", "o4"},
+			wantFinal: "x...o....x",
+		},
+		{
+			name:      "takeback of one move",
+			rows:      historyRows(".........X", "x........o", "x...o....x", "x...o...xo", "x...o....x", "x.x.o....o"),
+			wantMoves: []string{"x0", "o4", "x2"},
+			wantFinal: "x.x.o....o",
+		},
+		{
+			name:      "takeback of two moves",
+			rows:      historyRows(".........X", "x........o", "x...o....x", "x...o...xo", "x........o", "x.o......x"),
+			wantMoves: []string{"x0", "o2"},
+			wantFinal: "x.o......x",
+		},
+		{
+			name:      "takeback to the empty board",
+			rows:      historyRows(".........X", "x........o", ".........X"),
+			wantMoves: nil,
+			wantFinal: ".........X",
+		},
+	}
+	for _, tt := range tests {
+		steps := Replay(tt.rows)
+		var moves []string
+		for i, step := range steps {
+			if step.Step != i {
+				t.Errorf("%s: step %d numbered %d", tt.name, i, step.Step)
+			}
+			if step.Move != nil {
+				moves = append(moves, step.Move.Move)
+			}
+		}
+		if !reflect.DeepEqual(moves, tt.wantMoves) {
+			t.Errorf("%s: got moves %v, want %v", tt.name, moves, tt.wantMoves)
+		}
+		if final := steps[len(steps)-1].GameState; final != tt.wantFinal {
+			t.Errorf("%s: got final state %s, want %s", tt.name, final, tt.wantFinal)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	store "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
+)
+
+// Invite lifetimes.
+const (
+	DefaultInviteTTL = 24 * time.Hour
+	MaxInviteTTL     = 7 * 24 * time.Hour
+)
+
+// inviteCodeBank leaves out characters that are easy to misread (0/O, 1/I/L).
+const inviteCodeBank = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+var (
+	ErrInviteNotFound = errors.New("invite not found")
+	ErrInviteExpired  = errors.New("invite has expired")
+	ErrInviteUsed     = errors.New("invite has already been used or revoked")
+	ErrOwnInvite      = errors.New("cannot accept your own invite")
+)
+
+// CreateInvite makes an invite to the free seat of gameID. Only a seated
+// player can invite and the invite lasts ttl (DefaultInviteTTL when 0).
+func CreateInvite(gameID, creatorID string, ttl time.Duration) (store.Invite, error) {
+	if ttl == 0 {
+		ttl = DefaultInviteTTL
+	}
+	if ttl < 0 || ttl > MaxInviteTTL {
+		return store.Invite{}, errors.New("invite lifetime must be at most 7 days")
+	}
+	gameState, err := store.GetGameState(gameID)
+	if err != nil {
+		log.Println("[CreateInvite] Failed to get game state: ", err)
+		return store.Invite{}, err
+	}
+	if creatorID != gameState.PlayerX && creatorID != gameState.PlayerO {
+		return store.Invite{}, errors.New("only a seated player can invite")
+	}
+	if gameState.PlayerX != "" && gameState.PlayerO != "" {
+		return store.Invite{}, ErrSeatsTaken
+	}
+	now := time.Now()
+	invite := store.Invite{
+		Code:      randomString(inviteCodeBank, 6),
+		Token:     randomString(inviteCodeBank+"abcdefghijkmnpqrstuvwxyz", 24),
+		GameID:    gameID,
+		CreatorID: creatorID,
+		CreatedAt: now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+	}
+	if err := store.CreateInvite(invite); err != nil {
+		return store.Invite{}, err
+	}
+	return invite, nil
+}
+
+// RedeemInvite checks an invite code or link token, uses it up and seats
+// playerID in the free seat of its game. If the player cannot be seated
+// the invite is left unused.
+func RedeemInvite(codeOrToken, playerID string) (store.Invite, store.GameState, error) {
+	invite, err := findInvite(codeOrToken)
+	if err != nil {
+		return store.Invite{}, store.GameState{}, err
+	}
+	if time.Now().Unix() > invite.ExpiresAt {
+		return store.Invite{}, store.GameState{}, ErrInviteExpired
+	}
+	if playerID == invite.CreatorID {
+		return store.Invite{}, store.GameState{}, ErrOwnInvite
+	}
+	ok, err := store.UseInvite(invite.Code)
+	if err != nil {
+		return store.Invite{}, store.GameState{}, err
+	}
+	if !ok {
+		return store.Invite{}, store.GameState{}, ErrInviteUsed
+	}
+	gameState, err := SeatPlayer(invite.GameID, playerID, "")
+	if err != nil {
+		log.Println("[RedeemInvite] Failed to seat player, releasing invite: ", invite.Code, " ", err)
+		if releaseErr := store.ReleaseInvite(invite.Code); releaseErr != nil {
+			log.Println("[RedeemInvite] Failed to release invite: ", releaseErr)
+		}
+		return store.Invite{}, store.GameState{}, err
+	}
+	log.Println("[RedeemInvite] Invite redeemed: ", invite.Code, " for game ", invite.GameID)
+	return invite, gameState, nil
+}
+
+// RevokeInvite cancels an unused invite. Only its creator may revoke it.
+func RevokeInvite(codeOrToken, playerID string) error {
+	invite, err := findInvite(codeOrToken)
+	if err != nil {
+		return err
+	}
+	if invite.CreatorID != playerID {
+		return errors.New("only the creator can revoke an invite")
+	}
+	ok, err := store.RevokeInvite(invite.Code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInviteUsed
+	}
+	log.Println("[RevokeInvite] Invite revoked: ", invite.Code)
+	return nil
+}
+
+func findInvite(codeOrToken string) (store.Invite, error) {
+	if len(codeOrToken) == 6 {
+		codeOrToken = strings.ToUpper(codeOrToken) // codes are typed by hand
+	}
+	invite, err := store.GetInvite(codeOrToken)
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.Invite{}, ErrInviteNotFound
+	}
+	if err != nil {
+		log.Println("[findInvite] Failed to get invite: ", err)
+		return store.Invite{}, err
+	}
+	if invite.Used || invite.Revoked {
+		return store.Invite{}, ErrInviteUsed
+	}
+	return invite, nil
+}
+
+func randomString(bank string, n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		k, _ := rand.Int(rand.Reader, big.NewInt(int64(len(bank))))
+		b[i] = bank[k.Int64()]
+	}
+	return string(b)
+}
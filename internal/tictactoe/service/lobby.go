@@ -0,0 +1,131 @@
+package service
+
+import (
+	"log"
+	"sort"
+	"sync"
+
+	store "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
+)
+
+// LobbyGame is a game with one player seated that is waiting for an opponent.
+type LobbyGame struct {
+	GameID    string `json:"gameId"`
+	Host      string `json:"host"`     // player UUID of the seated player
+	OpenSide  string `json:"openSide"` // side the joining player gets
+	Mode      string `json:"mode"`
+	Variant   string `json:"variant"`
+	Rows      int    `json:"rows"`
+	Cols      int    `json:"cols"`
+	K         int    `json:"k"`
+	MoveTime  int    `json:"moveTime,omitempty"`
+	BaseTime  int    `json:"baseTime,omitempty"`
+	Increment int    `json:"increment,omitempty"`
+	BestOf    int    `json:"bestOf,omitempty"`
+	OpenedAt  int64  `json:"openedAt"` // unix seconds the host sat down
+}
+
+// LobbyEntry reports whether a game belongs in the lobby and describes it.
+// Games against the computer never do.
+func LobbyEntry(gameID string, gameState store.GameState) (LobbyGame, bool) {
+	if gameState.State == "" || gameState.Status != StatusActive || gameState.Config.IsAi {
+		return LobbyGame{}, false
+	}
+	game := LobbyGame{
+		GameID:    gameID,
+		Mode:      gameState.Config.Mode,
+		Variant:   gameState.Config.Variant,
+		Rows:      gameState.Config.Rows,
+		Cols:      gameState.Config.Cols,
+		K:         gameState.Config.K,
+		MoveTime:  gameState.Config.MoveTime,
+		BaseTime:  gameState.Config.BaseTime,
+		Increment: gameState.Config.Increment,
+		BestOf:    gameState.Config.BestOf,
+		OpenedAt:  gameState.LastUpdate,
+	}
+	switch {
+	case gameState.PlayerX != "" && gameState.PlayerO == "":
+		game.Host, game.OpenSide = gameState.PlayerX, "o"
+	case gameState.PlayerO != "" && gameState.PlayerX == "":
+		game.Host, game.OpenSide = gameState.PlayerO, "x"
+	default:
+		return LobbyGame{}, false
+	}
+	return game, true
+}
+
+// openGames indexes the games in the lobby so listing it does not read
+// every stored game. It is loaded from disk on first use and kept current
+// by SeatPlayer and endGame, the only places a game enters or leaves it.
+var openGames struct {
+	sync.Mutex
+	games  map[string]LobbyGame
+	loaded bool
+}
+
+// loadOpenGames fills the index from the stored games. The caller holds its lock.
+func loadOpenGames() error {
+	if openGames.loaded {
+		return nil
+	}
+	ids, err := store.ListGames()
+	if err != nil {
+		log.Println("[loadOpenGames] Failed to list games: ", err)
+		return err
+	}
+	openGames.games = make(map[string]LobbyGame)
+	for _, gameID := range ids {
+		gameState, err := store.GetGameState(gameID)
+		if err != nil {
+			log.Println("[loadOpenGames] Skipping unreadable game: ", gameID, " ", err)
+			continue
+		}
+		if game, ok := LobbyEntry(gameID, gameState); ok {
+			openGames.games[gameID] = game
+		}
+	}
+	openGames.loaded = true
+	log.Println("[loadOpenGames] ", len(openGames.games), " games open")
+	return nil
+}
+
+// indexLobby records whether a game whose seats or status just changed is open.
+func indexLobby(gameID string, gameState store.GameState) {
+	openGames.Lock()
+	defer openGames.Unlock()
+	if err := loadOpenGames(); err != nil {
+		return
+	}
+	if game, ok := LobbyEntry(gameID, gameState); ok {
+		openGames.games[gameID] = game
+	} else {
+		delete(openGames.games, gameID)
+	}
+}
+
+// OpenGame returns the lobby entry of a game that is waiting for an opponent.
+func OpenGame(gameID string) (LobbyGame, bool) {
+	openGames.Lock()
+	defer openGames.Unlock()
+	if err := loadOpenGames(); err != nil {
+		return LobbyGame{}, false
+	}
+	game, ok := openGames.games[gameID]
+	return game, ok
+}
+
+// ListLobby returns the open games, newest first.
+func ListLobby() ([]LobbyGame, error) {
+	openGames.Lock()
+	defer openGames.Unlock()
+	if err := loadOpenGames(); err != nil {
+		return nil, err
+	}
+	games := make([]LobbyGame, 0, len(openGames.games))
+	for _, game := range openGames.games {
+		games = append(games, game)
+	}
+	sort.Slice(games, func(i, j int) bool { return games[i].OpenedAt > games[j].OpenedAt })
+	return games, nil
+}
@@ -0,0 +1,40 @@
+package service
+
+import "sync"
+
+// gameLock serializes the writes to one game, so reading the latest row,
+// checking it and appending the next one cannot interleave with another
+// writer doing the same.
+type gameLock struct {
+	mu    sync.Mutex
+	users int // holders and waiters; the entry is dropped when none are left
+}
+
+var (
+	gameLocksMu sync.Mutex
+	gameLocks   = map[string]*gameLock{}
+)
+
+// lockGame blocks until the caller holds gameID's lock and returns the
+// function that releases it. Every exported function that changes a game
+// takes the lock; the unexported helpers they share expect it to be held.
+func lockGame(gameID string) (unlock func()) {
+	gameLocksMu.Lock()
+	lock, ok := gameLocks[gameID]
+	if !ok {
+		lock = &gameLock{}
+		gameLocks[gameID] = lock
+	}
+	lock.users++
+	gameLocksMu.Unlock()
+	lock.mu.Lock()
+	return func() {
+		lock.mu.Unlock()
+		gameLocksMu.Lock()
+		lock.users--
+		if lock.users == 0 {
+			delete(gameLocks, gameID)
+		}
+		gameLocksMu.Unlock()
+	}
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestLockGame(t *testing.T) {
+	var wg sync.WaitGroup
+	inside := map[string]int{}
+	var insideMu sync.Mutex
+	for i := 0; i < 50; i++ {
+		for _, gameID := range []string{"a", "b"} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				unlock := lockGame(gameID)
+				defer unlock()
+				insideMu.Lock()
+				inside[gameID]++
+				if inside[gameID] > 1 {
+					t.Errorf("two writers inside game %s", gameID)
+				}
+				insideMu.Unlock()
+				insideMu.Lock()
+				inside[gameID]--
+				insideMu.Unlock()
+			}()
+		}
+	}
+	wg.Wait()
+	gameLocksMu.Lock()
+	defer gameLocksMu.Unlock()
+	if len(gameLocks) != 0 {
+		t.Errorf("got %d locks left after every writer finished, want 0", len(gameLocks))
+	}
+}
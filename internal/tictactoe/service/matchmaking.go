@@ -0,0 +1,296 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	store "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
+)
+
+// Queue timeouts.
+const (
+	DefaultQueueTimeout = time.Minute
+	MaxQueueTimeout     = 10 * time.Minute
+)
+
+// QueueEntry is a player waiting for a random opponent.
+type QueueEntry struct {
+	PlayerID   string
+	Side       string // x, o or empty for either
+	Rating     int    // looked up by Join; whatever the caller sets is ignored
+	MinRating  int    // lowest opponent rating accepted; 0 for no limit
+	MaxRating  int    // highest opponent rating accepted; 0 for no limit
+	Mode       string
+	Variant    string
+	Timeout    time.Duration // how long to wait; DefaultQueueTimeout when 0
+	AIFallback bool          // play the computer instead of timing out
+	Bot        string        // bot used for the fallback; DefaultBot when empty
+
+	joined time.Time
+	timer  *time.Timer
+}
+
+// Match is a game created by the matchmaker.
+type Match struct {
+	GameID  string `json:"gameId"`
+	PlayerX string `json:"playerX"`
+	PlayerO string `json:"playerO"`
+}
+
+// Matchmaker pairs queued players. OnMatch is called for every game it
+// creates, including AI fallbacks, and OnTimeout for players who waited
+// out their timeout without a fallback.
+type Matchmaker struct {
+	OnMatch   func(match Match)
+	OnTimeout func(playerID string)
+
+	mu      sync.Mutex
+	queue   []*QueueEntry
+	matches map[string]Match // each player's latest match until they queue again
+}
+
+func NewMatchmaker() *Matchmaker {
+	return &Matchmaker{matches: make(map[string]Match)}
+}
+
+// Join queues the entry, replacing any earlier entry for the player, or
+// pairs it at once with the longest waiting compatible player.
+// matched is false while the player is left waiting.
+func (m *Matchmaker) Join(entry QueueEntry) (match Match, matched bool, err error) {
+	if err := entry.Validate(); err != nil {
+		return Match{}, false, err
+	}
+	entry = entry.withDefaults()
+	rating, err := RatingOf(entry.PlayerID)
+	if err != nil {
+		return Match{}, false, err
+	}
+	entry.Rating = rating
+	entry.joined = time.Now()
+	m.mu.Lock()
+	m.remove(entry.PlayerID)
+	delete(m.matches, entry.PlayerID)
+	var opponent *QueueEntry
+	for i, waiting := range m.queue {
+		if compatible(waiting, &entry) {
+			opponent = waiting
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+			opponent.timer.Stop()
+			break
+		}
+	}
+	if opponent == nil {
+		queued := entry
+		m.enqueue(&queued)
+		log.Println("[Matchmaker] Player queued: ", entry.PlayerID, " queue length: ", len(m.queue))
+		m.mu.Unlock()
+		return Match{}, false, nil
+	}
+	m.mu.Unlock()
+	match, err = createMatch(opponent, &entry)
+	if err != nil {
+		log.Println("[Matchmaker] Failed to create match, requeueing player: ", opponent.PlayerID, " ", err)
+		m.mu.Lock()
+		if !m.queued(opponent.PlayerID) {
+			m.enqueue(opponent)
+		}
+		m.mu.Unlock()
+		return Match{}, false, err
+	}
+	m.matched(match)
+	return match, true, nil
+}
+
+// LastMatch returns the game the player was most recently matched into.
+func (m *Matchmaker) LastMatch(playerID string) (Match, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	match, ok := m.matches[playerID]
+	return match, ok
+}
+
+// matched records a new match and reports it.
+func (m *Matchmaker) matched(match Match) {
+	m.mu.Lock()
+	m.matches[match.PlayerX] = match
+	m.matches[match.PlayerO] = match
+	delete(m.matches, AIPlayerID)
+	m.mu.Unlock()
+	if m.OnMatch != nil {
+		m.OnMatch(match)
+	}
+}
+
+// Leave takes the player out of the queue. Returns false if they were not in it.
+func (m *Matchmaker) Leave(playerID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.remove(playerID)
+}
+
+// Waiting reports whether the player is in the queue.
+func (m *Matchmaker) Waiting(playerID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.queued(playerID)
+}
+
+// Validate reports what is wrong with a request to join the queue. Empty
+// fields are fine; Join fills in their defaults.
+func (e QueueEntry) Validate() error {
+	if e.Side != "" && e.Side != "x" && e.Side != "o" {
+		return errors.New("side must be x or o")
+	}
+	if e.Timeout < 0 || e.Timeout > MaxQueueTimeout {
+		return errors.New("timeout must be at most 10 minutes")
+	}
+	e = e.withDefaults()
+	if !IsMode(e.Mode) || !IsVariant(e.Variant) {
+		return errors.New("unknown mode or variant")
+	}
+	if e.AIFallback {
+		if bot, ok := GetBot(e.Bot); !ok || !BotSupports(bot, e.Mode, e.Variant) {
+			return errors.New("bot cannot play this game: " + e.Bot)
+		}
+	}
+	return nil
+}
+
+// withDefaults fills in the fields a player left empty.
+func (e QueueEntry) withDefaults() QueueEntry {
+	if e.Timeout == 0 {
+		e.Timeout = DefaultQueueTimeout
+	}
+	if e.Mode == "" {
+		e.Mode = ModeClassic
+	}
+	if e.Variant == "" {
+		e.Variant = VariantStandard
+	}
+	if e.AIFallback && e.Bot == "" {
+		e.Bot = DefaultBot
+	}
+	return e
+}
+
+// enqueue puts entry back in join order and starts the timer for what is
+// left of its timeout; m.mu must be held.
+func (m *Matchmaker) enqueue(entry *QueueEntry) {
+	i := len(m.queue)
+	for i > 0 && m.queue[i-1].joined.After(entry.joined) {
+		i--
+	}
+	m.queue = append(m.queue[:i], append([]*QueueEntry{entry}, m.queue[i:]...)...)
+	entry.timer = time.AfterFunc(time.Until(entry.joined.Add(entry.Timeout)), func() { m.expire(entry) })
+}
+
+// queued reports whether the player has an entry; m.mu must be held.
+func (m *Matchmaker) queued(playerID string) bool {
+	for _, waiting := range m.queue {
+		if waiting.PlayerID == playerID {
+			return true
+		}
+	}
+	return false
+}
+
+// remove drops the player's entry; m.mu must be held.
+func (m *Matchmaker) remove(playerID string) bool {
+	for i, waiting := range m.queue {
+		if waiting.PlayerID == playerID {
+			waiting.timer.Stop()
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// expire handles an entry whose timeout passed without a match.
+func (m *Matchmaker) expire(entry *QueueEntry) {
+	m.mu.Lock()
+	found := false
+	for i, waiting := range m.queue {
+		if waiting == entry {
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+			found = true
+			break
+		}
+	}
+	m.mu.Unlock()
+	if !found {
+		return
+	}
+	if !entry.AIFallback {
+		log.Println("[Matchmaker] Queue timed out for player: ", entry.PlayerID)
+		if m.OnTimeout != nil {
+			m.OnTimeout(entry.PlayerID)
+		}
+		return
+	}
+	log.Println("[Matchmaker] No opponent found, player ", entry.PlayerID, " plays the computer")
+	match, err := createMatch(entry, nil)
+	if err != nil {
+		log.Println("[Matchmaker] Failed to create AI game: ", err)
+		if m.OnTimeout != nil {
+			m.OnTimeout(entry.PlayerID)
+		}
+		return
+	}
+	m.matched(match)
+}
+
+// compatible reports whether two entries can play each other.
+func compatible(a, b *QueueEntry) bool {
+	if a.PlayerID == b.PlayerID || a.Mode != b.Mode || a.Variant != b.Variant {
+		return false
+	}
+	if a.Side != "" && a.Side == b.Side {
+		return false
+	}
+	return inRange(b.Rating, a.MinRating, a.MaxRating) && inRange(a.Rating, b.MinRating, b.MaxRating)
+}
+
+func inRange(rating, min, max int) bool {
+	return (min == 0 || rating >= min) && (max == 0 || rating <= max)
+}
+
+// createMatch creates a classic-size game for the two entries and seats them.
+// With no second entry the computer takes the other side; games between two
+// players are rated.
+func createMatch(first, second *QueueEntry) (Match, error) {
+	config := store.GameConfig{Mode: first.Mode, Variant: first.Variant, Rows: ClassicBoard.Rows, Cols: ClassicBoard.Cols, K: ClassicBoard.K, Rated: second != nil}
+	if second == nil {
+		config.IsAi = true
+		config.Bot = first.Bot
+	}
+	gameID, err := NewGame(config)
+	if err != nil {
+		return Match{}, err
+	}
+	//whoever asked for a side gets it; otherwise toss a coin
+	firstSide := first.Side
+	if firstSide == "" && second != nil && second.Side != "" {
+		firstSide = map[string]string{"x": "o", "o": "x"}[second.Side]
+	}
+	if firstSide == "" {
+		firstSide = []string{"x", "o"}[rand.Intn(2)]
+	}
+	var gameState store.GameState
+	switch {
+	case second == nil:
+		gameState, err = SeatPlayer(gameID, first.PlayerID, firstSide)
+	case firstSide == "x":
+		gameState, err = seatPlayers(gameID, first.PlayerID, second.PlayerID)
+	default:
+		gameState, err = seatPlayers(gameID, second.PlayerID, first.PlayerID)
+	}
+	if err != nil {
+		return Match{}, err
+	}
+	log.Println("[Matchmaker] Match created: ", gameID, " x: ", gameState.PlayerX, " o: ", gameState.PlayerO)
+	return Match{GameID: gameID, PlayerX: gameState.PlayerX, PlayerO: gameState.PlayerO}, nil
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEnqueueKeepsJoinOrder(t *testing.T) {
+	start := time.Now()
+	tests := []struct {
+		name    string
+		queued  []string // players already waiting, in join order
+		requeue string   // player put back after a failed match
+		want    []string
+	}{
+		{"longest waiting goes back to the front", []string{"b", "c"}, "a", []string{"a", "b", "c"}},
+		{"between earlier and later players", []string{"a", "c"}, "b", []string{"a", "b", "c"}},
+		{"newest goes to the back", []string{"a", "b"}, "c", []string{"a", "b", "c"}},
+		{"alone", nil, "a", []string{"a"}},
+	}
+	joined := map[string]time.Time{"a": start, "b": start.Add(time.Second), "c": start.Add(2 * time.Second)}
+	for _, tt := range tests {
+		m := NewMatchmaker()
+		m.mu.Lock()
+		for _, player := range append(tt.queued, tt.requeue) {
+			m.enqueue(&QueueEntry{PlayerID: player, joined: joined[player], Timeout: time.Hour})
+		}
+		var got []string
+		for _, entry := range m.queue {
+			got = append(got, entry.PlayerID)
+			entry.timer.Stop()
+		}
+		m.mu.Unlock()
+		if len(got) != len(tt.want) {
+			t.Fatalf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
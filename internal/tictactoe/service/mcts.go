@@ -0,0 +1,164 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"math"
+	"math/rand"
+
+	store "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
+)
+
+// mctsBot runs Monte Carlo tree search (UCT) with random playouts.
+type mctsBot struct {
+	iterations int
+}
+
+// mctsAction is one candidate move: a piece placed at a cell.
+type mctsAction struct {
+	pos   int
+	piece byte
+}
+
+// mctsNode is one position in the search tree. side is the seat that made
+// the move leading to it, and score is counted from side's point of view.
+type mctsNode struct {
+	parent   *mctsNode
+	children []*mctsNode
+	untried  []mctsAction
+	action   mctsAction
+	side     byte
+	done     bool // the move completed a line
+	visits   int
+	score    float64
+}
+
+func (mctsBot) Supports(string, string) bool { return true }
+
+func (b mctsBot) Move(gameState store.GameState) (string, error) {
+	rules := RulesFor(gameState.Config)
+	variant := VariantFor(gameState.Config)
+	state := []byte(gameState.State)
+	if len(state) != len(rules.InitialState()) {
+		return "", errors.New("invalid game state")
+	}
+	me := Turn(gameState.State)
+	root := &mctsNode{untried: searchActions(rules, variant, state), side: otherPiece(me)}
+	if len(root.untried) == 0 {
+		return "", errors.New("no moves available")
+	}
+	for i := 0; i < b.iterations; i++ {
+		scratch := append([]byte(nil), state...)
+		node := root
+		// selection
+		for len(node.untried) == 0 && len(node.children) > 0 {
+			node = node.bestChild(math.Sqrt2)
+			rules.Place(scratch, node.action.piece, node.action.pos)
+		}
+		// expansion
+		if len(node.untried) > 0 {
+			k := rand.Intn(len(node.untried))
+			action := node.untried[k]
+			node.untried = append(node.untried[:k], node.untried[k+1:]...)
+			side := Turn(string(scratch))
+			rules.Place(scratch, action.piece, action.pos)
+			child := &mctsNode{parent: node, action: action, side: side}
+			if _, done := rules.WinningLine(scratch, action.pos); done {
+				child.done = true
+			} else {
+				child.untried = searchActions(rules, variant, scratch)
+			}
+			node.children = append(node.children, child)
+			node = child
+		}
+		// simulation
+		var winner byte
+		if node.done {
+			winner = variant.Winner(node.side)
+		} else {
+			winner = playout(rules, variant, scratch)
+		}
+		// backpropagation
+		for n := node; n != nil; n = n.parent {
+			n.visits++
+			switch winner {
+			case n.side:
+				n.score++
+			case '.':
+				n.score += 0.5
+			}
+		}
+	}
+	best := root.bestChild(0)
+	log.Println("[mctsBot] Iterations: ", b.iterations, " Move: ", best.action.pos, " Visits: ", best.visits)
+	return formatMove(best.action.piece, best.action.pos), nil
+}
+
+// searchActions are the moves worth expanding: on boards won by lines the
+// candidate squares, otherwise every legal move, with each piece the side
+// to move may place.
+func searchActions(rules Rules, variant Variant, state []byte) []mctsAction {
+	var cells []int
+	if board, ok := rules.(lineBoard); ok {
+		cells = board.candidateSquares(state[:board.Cells()])
+	} else {
+		cells = rules.LegalMoves(state)
+	}
+	var actions []mctsAction
+	for _, pos := range cells {
+		for _, piece := range variant.Pieces(Turn(string(state))) {
+			actions = append(actions, mctsAction{pos: pos, piece: piece})
+		}
+	}
+	return actions
+}
+
+// bestChild picks the child with the highest UCB1 value; with c == 0 it
+// simply returns the best average score.
+func (n *mctsNode) bestChild(c float64) *mctsNode {
+	var best *mctsNode
+	bestValue := math.Inf(-1)
+	for _, child := range n.children {
+		value := child.score/float64(child.visits) + c*math.Sqrt(math.Log(float64(n.visits))/float64(child.visits))
+		if value > bestValue {
+			best, bestValue = child, value
+		}
+	}
+	return best
+}
+
+// playout plays random moves until the game ends and returns the winning
+// side or '.' for a draw.
+func playout(rules Rules, variant Variant, state []byte) byte {
+	if board, ok := rules.(Board); ok {
+		// classic boards never restrict moves, so shuffle once instead of asking every turn
+		open := openSquares(state[:board.Cells()])
+		rand.Shuffle(len(open), func(i, j int) { open[i], open[j] = open[j], open[i] })
+		for _, pos := range open {
+			if winner, done := playoutMove(board, variant, state, pos); done {
+				return winner
+			}
+		}
+		return '.'
+	}
+	for {
+		moves := rules.LegalMoves(state)
+		if len(moves) == 0 {
+			return '.'
+		}
+		if winner, done := playoutMove(rules, variant, state, moves[rand.Intn(len(moves))]); done {
+			return winner
+		}
+	}
+}
+
+// playoutMove places a random allowed piece at pos and reports the winner if it ended the game.
+func playoutMove(rules Rules, variant Variant, state []byte, pos int) (byte, bool) {
+	side := Turn(string(state))
+	pieces := variant.Pieces(side)
+	rules.Place(state, pieces[rand.Intn(len(pieces))], pos)
+	if _, done := rules.WinningLine(state, pos); done {
+		return variant.Winner(side), true
+	}
+	return 0, false
+}
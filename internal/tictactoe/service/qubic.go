@@ -0,0 +1,115 @@
+package service
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// qubicRules is qubic: 4x4x4 tic-tac-toe where four in a row along any of
+// the 76 lines through the cube wins.
+//
+// State encoding (65 chars): 64 cells numbered layer*16 + row*4 + col, then
+// the turn marker, e.g. layer 1, row 2, col 3 is cell 27.
+type qubicRules struct{}
+
+const (
+	qubicSize  = 4
+	qubicCells = qubicSize * qubicSize * qubicSize
+)
+
+var qubicGeometry = generateQubicGeometry()
+
+// generateQubicGeometry walks the 13 distinct directions in the cube from
+// every cell and keeps each run of four that stays inside it.
+func generateQubicGeometry() *boardGeometry {
+	g := &boardGeometry{linesThrough: make([][][]int, qubicCells)}
+	for dl := -1; dl <= 1; dl++ {
+		for dr := -1; dr <= 1; dr++ {
+			for dc := -1; dc <= 1; dc++ {
+				//only one of each pair of opposite directions
+				if dl < 0 || (dl == 0 && dr < 0) || (dl == 0 && dr == 0 && dc <= 0) {
+					continue
+				}
+				for start := 0; start < qubicCells; start++ {
+					l, r, c := start/16, start/4%4, start%4
+					endL, endR, endC := l+dl*(qubicSize-1), r+dr*(qubicSize-1), c+dc*(qubicSize-1)
+					if endL < 0 || endL >= qubicSize || endR < 0 || endR >= qubicSize || endC < 0 || endC >= qubicSize {
+						continue
+					}
+					line := make([]int, qubicSize)
+					for i := range line {
+						line[i] = (l+dl*i)*16 + (r+dr*i)*4 + c + dc*i
+					}
+					g.lines = append(g.lines, line)
+					for _, pos := range line {
+						g.linesThrough[pos] = append(g.linesThrough[pos], line)
+					}
+				}
+			}
+		}
+	}
+	return g
+}
+
+func (qubicRules) InitialState() string {
+	return strings.Repeat(".", qubicCells) + "X"
+}
+
+func (qubicRules) Cells() int { return qubicCells }
+
+// ParseMove accepts a cell index ("x27") or a layer, row and column ("x1,2,3").
+func (qubicRules) ParseMove(move string) (byte, int, error) {
+	if len(move) < 2 {
+		return 0, 0, errors.New("malformed move: " + move)
+	}
+	piece := strings.ToLower(move[:1])[0]
+	if piece != 'x' && piece != 'o' {
+		return 0, 0, errors.New("malformed move: " + move)
+	}
+	if parts := strings.Split(move[1:], ","); len(parts) == 3 {
+		pos := 0
+		for _, part := range parts {
+			n, err := strconv.Atoi(part)
+			if err != nil || n < 0 || n >= qubicSize {
+				return 0, 0, errors.New("malformed move: " + move)
+			}
+			pos = pos*qubicSize + n
+		}
+		return piece, pos, nil
+	}
+	pos, err := strconv.Atoi(move[1:])
+	if err != nil || pos < 0 || pos >= qubicCells {
+		return 0, 0, errors.New("malformed move: " + move)
+	}
+	return piece, pos, nil
+}
+
+// LegalMoves is every open cell.
+func (qubicRules) LegalMoves(state []byte) []int {
+	return openSquares(state[:qubicCells])
+}
+
+// Place puts piece at pos and passes the turn.
+func (qubicRules) Place(state []byte, piece byte, pos int) {
+	state[pos] = piece
+	passTurn(state)
+}
+
+// WinningLine returns the four cells completed by the piece at pos.
+func (qubicRules) WinningLine(state []byte, pos int) ([]int, bool) {
+	return qubicGeometry.lineThrough(state[:qubicCells], state[pos], pos)
+}
+
+func (qubicRules) geometry() *boardGeometry { return qubicGeometry }
+
+// candidateSquares lists every open cell, those on the most lines first,
+// which helps alpha-beta prune (the 8 corners and 8 center cells lie on 7).
+func (qubicRules) candidateSquares(cells []byte) []int {
+	open := openSquares(cells)
+	sort.SliceStable(open, func(i, j int) bool {
+		return len(qubicGeometry.linesThrough[open[i]]) > len(qubicGeometry.linesThrough[open[j]])
+	})
+	return open
+}
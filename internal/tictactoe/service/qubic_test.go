@@ -0,0 +1,53 @@
+package service
+
+import "testing"
+
+func TestQubicParseMove(t *testing.T) {
+	tests := []struct {
+		move    string
+		piece   byte
+		pos     int
+		wantErr bool
+	}{
+		{"x27", 'x', 27, false},
+		{"o1,2,3", 'o', 27, false},
+		{"X3,3,3", 'x', 63, false},
+		{"x64", 0, 0, true},
+		{"x1,2,4", 0, 0, true},
+		{"x1,2", 0, 0, true},
+		{"z0", 0, 0, true},
+	}
+	for _, tt := range tests {
+		piece, pos, err := qubicRules{}.ParseMove(tt.move)
+		if (err != nil) != tt.wantErr || piece != tt.piece || pos != tt.pos {
+			t.Errorf("%s: got %c %d %v, want %c %d error %v", tt.move, piece, pos, err, tt.piece, tt.pos, tt.wantErr)
+		}
+	}
+}
+
+func TestQubicWinningLine(t *testing.T) {
+	if got := len(qubicGeometry.lines); got != 76 {
+		t.Fatalf("got %d lines, want 76", got)
+	}
+	tests := []struct {
+		name  string
+		cells []int // cells holding x
+		pos   int
+		want  bool
+	}{
+		{"row", []int{0, 1, 2, 3}, 3, true},
+		{"pillar through the layers", []int{5, 21, 37, 53}, 37, true},
+		{"space diagonal", []int{0, 21, 42, 63}, 42, true},
+		{"three of four", []int{0, 21, 42}, 42, false},
+		{"bent", []int{0, 1, 2, 7}, 2, false},
+	}
+	for _, tt := range tests {
+		state := []byte(qubicRules{}.InitialState())
+		for _, pos := range tt.cells {
+			state[pos] = 'x'
+		}
+		if _, got := (qubicRules{}).WinningLine(state, tt.pos); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
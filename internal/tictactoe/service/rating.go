@@ -0,0 +1,77 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"math"
+	"sync"
+
+	store "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
+)
+
+// DefaultRating is the rating of a player who has not finished a rated game.
+const DefaultRating = 1500
+
+// ratingK is the Elo K-factor: the most a single game can move a rating.
+const ratingK = 32
+
+// ratingsMu serialises reading and updating ratings, so two games ending
+// at once for the same player do not overwrite each other's change.
+var ratingsMu sync.Mutex
+
+// RatingOf returns the player's stored rating, or DefaultRating.
+func RatingOf(playerID string) (int, error) {
+	rating, err := store.GetRating(playerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DefaultRating, nil
+	}
+	if err != nil {
+		log.Println("[RatingOf] Failed to get rating: ", err)
+		return 0, err
+	}
+	return rating.Rating, nil
+}
+
+// rateGame updates both players' ratings once a rated game has finished.
+// Failing to store them is logged and does not undo the result.
+func rateGame(gameState store.GameState) {
+	if !gameState.Config.Rated || gameState.PlayerX == AIPlayerID || gameState.PlayerO == AIPlayerID {
+		return
+	}
+	outcome, _ := ParseStatus(gameState.Status)
+	var score float64 // x's score
+	switch outcome {
+	case StatusActive:
+		return
+	case StatusTied:
+		score = 0.5
+	case gameState.PlayerX:
+		score = 1
+	}
+	ratingsMu.Lock()
+	defer ratingsMu.Unlock()
+	x, errX := ratingOrDefault(gameState.PlayerX)
+	o, errO := ratingOrDefault(gameState.PlayerO)
+	if errX != nil || errO != nil {
+		log.Println("[rateGame] Failed to get ratings, not rating game: ", errX, errO)
+		return
+	}
+	expected := 1 / (1 + math.Pow(10, float64(o.Rating-x.Rating)/400))
+	change := int(math.Round(ratingK * (score - expected)))
+	x.Rating, o.Rating = x.Rating+change, o.Rating-change
+	x.Games, o.Games = x.Games+1, o.Games+1
+	if err := store.SaveRatings(x, o); err != nil {
+		log.Println("[rateGame] Failed to save ratings: ", err)
+		return
+	}
+	log.Println("[rateGame] Ratings now x: ", x.Rating, " o: ", o.Rating)
+}
+
+func ratingOrDefault(playerID string) (store.Rating, error) {
+	rating, err := store.GetRating(playerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.Rating{PlayerID: playerID, Rating: DefaultRating}, nil
+	}
+	return rating, err
+}
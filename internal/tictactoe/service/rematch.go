@@ -0,0 +1,116 @@
+package service
+
+import (
+	"errors"
+	"log"
+
+	store "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
+)
+
+// MaxBestOf is the longest series a game can be created with.
+const MaxBestOf = 9
+
+// SeriesScore is the running score of a best-of-N series of linked games.
+type SeriesScore struct {
+	BestOf int            `json:"bestOf"`
+	Game   int            `json:"game"`  // number of the latest game in the series
+	Games  []string       `json:"games"` // game IDs, oldest first
+	Wins   map[string]int `json:"wins"`  // player UUID to games won
+	Draws  int            `json:"draws"`
+	Winner string         `json:"winner,omitempty"` // player UUID once they have won more than half
+}
+
+// ValidateBestOf checks the series length chosen at creation.
+func ValidateBestOf(bestOf int) error {
+	if bestOf < 0 || bestOf > MaxBestOf || (bestOf > 0 && bestOf%2 == 0) {
+		return errors.New("best of must be an odd number up to 9")
+	}
+	return nil
+}
+
+// Rematch creates the next game after gameID with X and O swapped, linked
+// to it, and returns the new game's ID. A rematch of a decided series, or
+// of a game not in one, starts a new series of the same length. Only one
+// rematch is ever linked to a game, however many ask for it at once.
+func Rematch(gameID string) (string, error) {
+	unlock := lockGame(gameID)
+	defer unlock()
+	previous, err := store.GetGameState(gameID)
+	if err != nil {
+		log.Println("[Rematch] Failed to get game state: ", err)
+		return "", err
+	}
+	if previous.Status == StatusActive {
+		return "", errors.New("game is still in progress")
+	}
+	if previous.Config.NextGame != "" {
+		return "", errors.New("rematch already created")
+	}
+	series, err := Series(gameID)
+	if err != nil {
+		return "", err
+	}
+	config := previous.Config
+	config.PreviousGame, config.NextGame = gameID, ""
+	config.SeriesGame = 1
+	if series.Winner == "" && previous.Config.SeriesGame < config.BestOf {
+		config.SeriesGame = previous.Config.SeriesGame + 1
+	}
+	newID, err := NewGame(config)
+	if err != nil {
+		log.Println("[Rematch] Failed to create game: ", err)
+		return "", err
+	}
+	gameState, err := store.GetGameState(newID)
+	if err != nil {
+		return "", err
+	}
+	gameState.PlayerX, gameState.PlayerO = previous.PlayerO, previous.PlayerX
+	if err := store.UpdateGameState(newID, gameState); err != nil {
+		log.Println("[Rematch] Failed to seat players: ", err)
+		return "", err
+	}
+	previous.Config.NextGame = newID
+	if err := store.UpdateConfig(gameID, previous.Config); err != nil {
+		log.Println("[Rematch] Failed to link games: ", err)
+		return "", err
+	}
+	log.Println("[Rematch] Game ", newID, " is a rematch of ", gameID, " (series game ", config.SeriesGame, ")")
+	return newID, nil
+}
+
+// Series scores the series gameID belongs to, counting only games up to
+// and including gameID. A game outside a series scores as a series of one.
+func Series(gameID string) (SeriesScore, error) {
+	gameState, err := store.GetGameState(gameID)
+	if err != nil {
+		log.Println("[Series] Failed to get game state: ", err)
+		return SeriesScore{}, err
+	}
+	score := SeriesScore{BestOf: max(gameState.Config.BestOf, 1), Game: gameState.Config.SeriesGame, Wins: map[string]int{}}
+	games := []store.GameState{gameState}
+	ids := []string{gameID}
+	for len(games) < score.Game && gameState.Config.PreviousGame != "" {
+		id := gameState.Config.PreviousGame
+		if gameState, err = store.GetGameState(id); err != nil {
+			log.Println("[Series] Failed to get previous game: ", err)
+			return SeriesScore{}, err
+		}
+		games = append([]store.GameState{gameState}, games...)
+		ids = append([]string{id}, ids...)
+	}
+	score.Games = ids
+	for _, game := range games {
+		switch outcome, _ := ParseStatus(game.Status); outcome {
+		case StatusActive:
+		case StatusTied:
+			score.Draws++
+		default:
+			score.Wins[outcome]++
+			if score.Wins[outcome] > score.BestOf/2 {
+				score.Winner = outcome
+			}
+		}
+	}
+	return score, nil
+}
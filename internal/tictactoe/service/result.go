@@ -0,0 +1,86 @@
+package service
+
+import (
+	store "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
+)
+
+const (
+	ResultInProgress = "in_progress"
+	ResultWon        = "won"
+	ResultDraw       = "draw"
+)
+
+// GameResult is where a game stands, in a form clients can use without
+// decoding Status or searching the board for the winning line.
+type GameResult struct {
+	Status       string `json:"status"`                 // in_progress, won or draw
+	Reason       string `json:"reason,omitempty"`       // how a finished game ended, e.g. line or resigned
+	Winner       string `json:"winner,omitempty"`       // x or o
+	WinnerID     string `json:"winnerId,omitempty"`     // player UUID of the winner
+	WinningLine  []int  `json:"winningLine,omitempty"`  // cell indices; sub-board indices in ultimate
+	MoveNumber   int    `json:"moveNumber"`             // moves played so far
+	Turn         string `json:"turn,omitempty"`         // side to move while in progress
+	TurnPlayerID string `json:"turnPlayerId,omitempty"` // player UUID of the side to move
+}
+
+// Result describes a tictactoe game's state.
+func Result(gameState store.GameState) GameResult {
+	rules := RulesFor(gameState.Config)
+	cells := []byte(gameState.State)
+	var line []int
+	if _, reason := ParseStatus(gameState.Status); reason == ReasonLine {
+		//the last move is not stored so look for any completed line
+		for pos := 0; pos < rules.Cells() && pos < len(cells); pos++ {
+			if cells[pos] == '.' {
+				continue
+			}
+			if found, ok := rules.WinningLine(cells, pos); ok {
+				line = found
+				break
+			}
+		}
+	}
+	return NewResult(gameState, line, MovesPlayed(cells, rules.Cells()))
+}
+
+// NewResult builds a GameResult from a game's seats and Status. line is the
+// completed line if any; a game won by forfeit has none.
+func NewResult(gameState store.GameState, line []int, moves int) GameResult {
+	result := GameResult{MoveNumber: moves}
+	outcome, reason := ParseStatus(gameState.Status)
+	result.Reason = reason
+	switch outcome {
+	case StatusActive:
+		result.Status = ResultInProgress
+		turn := Turn(gameState.State)
+		result.Turn = string(turn)
+		if turn == 'x' {
+			result.TurnPlayerID = gameState.PlayerX
+		} else {
+			result.TurnPlayerID = gameState.PlayerO
+		}
+	case StatusTied:
+		result.Status = ResultDraw
+	default:
+		result.Status = ResultWon
+		result.WinnerID = outcome
+		if outcome == gameState.PlayerX {
+			result.Winner = "x"
+		} else {
+			result.Winner = "o"
+		}
+		result.WinningLine = line
+	}
+	return result
+}
+
+// MovesPlayed counts the occupied cells among the first n cells of state.
+func MovesPlayed(state []byte, n int) int {
+	moves := 0
+	for pos := 0; pos < n && pos < len(state); pos++ {
+		if state[pos] != '.' {
+			moves++
+		}
+	}
+	return moves
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"errors"
+
+	store "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
+)
+
+// Game modes selectable at creation. Qubic, on a 4x4x4 cube, is a mode like
+// the others: its games are stored and played through the tictactoe endpoints.
+const (
+	ModeClassic  = "classic"
+	ModeUltimate = "ultimate"
+	ModeQubic    = "qubic"
+)
+
+// Modes lists every game mode.
+var Modes = []string{ModeClassic, ModeUltimate, ModeQubic}
+
+// Rules is how a game mode encodes its state string and judges moves.
+// Every state string ends with the turn marker; anything before that is
+// up to the mode. State slices passed in are modified in place.
+type Rules interface {
+	// InitialState is the state string of a new game.
+	InitialState() string
+	// Cells is the number of playable cells at the start of the state string.
+	Cells() int
+	// ParseMove reads a move such as "x4" into the piece and cell index.
+	ParseMove(move string) (byte, int, error)
+	// LegalMoves lists the cells the side to move may play.
+	LegalMoves(state []byte) []int
+	// Place puts piece at pos, updates any mode bookkeeping and passes the turn.
+	Place(state []byte, piece byte, pos int)
+	// WinningLine returns the line completed by the move just played at pos.
+	WinningLine(state []byte, pos int) ([]int, bool)
+}
+
+// RulesFor returns the rules stored in the game's config.
+func RulesFor(config store.GameConfig) Rules {
+	switch config.Mode {
+	case ModeUltimate:
+		return ultimateRules{}
+	case ModeQubic:
+		return qubicRules{}
+	}
+	return BoardFor(config)
+}
+
+// IsMode reports whether mode is a known game mode.
+func IsMode(mode string) bool {
+	for _, m := range Modes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// checkMove verifies that the side to move may place piece and pos is legal.
+func checkMove(rules Rules, variant Variant, state string, piece byte, pos int) error {
+	if len(state) != len(rules.InitialState()) {
+		return errors.New("invalid game state")
+	}
+	if !variant.allows(Turn(state), piece) {
+		return errors.New("piece not allowed")
+	}
+	for _, legal := range rules.LegalMoves([]byte(state)) {
+		if legal == pos {
+			return nil
+		}
+	}
+	return errors.New("invalid move")
+}
+
+// passTurn flips the turn marker at the end of the state.
+func passTurn(state []byte) {
+	last := len(state) - 1
+	if Turn(string(state)) == 'x' {
+		state[last] = 'o'
+	} else {
+		state[last] = 'x'
+	}
+}
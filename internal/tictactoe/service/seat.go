@@ -0,0 +1,72 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	store "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
+)
+
+// ErrSeatsTaken is returned when there is no free seat for a player.
+var ErrSeatsTaken = errors.New("players already chosen")
+
+// SeatPlayer puts playerID on the side they chose. Choosing x when X is
+// taken seats them on O instead; an empty choice takes whichever side is
+// free. In games against the computer it takes the remaining side.
+func SeatPlayer(gameID, playerID, choice string) (store.GameState, error) {
+	if choice != "" && choice != "x" && choice != "o" {
+		return store.GameState{}, errors.New("choice must be x or o")
+	}
+	unlock := lockGame(gameID)
+	defer unlock()
+	gameState, err := store.GetGameState(gameID)
+	if err != nil {
+		log.Println("[SeatPlayer] Failed to get game state: ", err)
+		return store.GameState{}, err
+	}
+	if choice != "o" && gameState.PlayerX == "" {
+		gameState.PlayerX = playerID
+	} else if gameState.PlayerO == "" {
+		gameState.PlayerO = playerID
+	} else {
+		return store.GameState{}, ErrSeatsTaken
+	}
+	if gameState.Config.IsAi {
+		gameState = SeatAI(gameState)
+	}
+	return storeSeats(gameID, gameState)
+}
+
+// seatPlayers fills both seats of a game nobody has sat down in yet with a
+// single stored row, so it is never seen, in the lobby or anywhere else,
+// with only one of them seated.
+func seatPlayers(gameID, playerX, playerO string) (store.GameState, error) {
+	unlock := lockGame(gameID)
+	defer unlock()
+	gameState, err := store.GetGameState(gameID)
+	if err != nil {
+		log.Println("[seatPlayers] Failed to get game state: ", err)
+		return store.GameState{}, err
+	}
+	if gameState.PlayerX != "" || gameState.PlayerO != "" {
+		return store.GameState{}, ErrSeatsTaken
+	}
+	gameState.PlayerX, gameState.PlayerO = playerX, playerO
+	return storeSeats(gameID, gameState)
+}
+
+// storeSeats appends gameState with its new seats and updates the lobby.
+// The caller holds the game's lock.
+func storeSeats(gameID string, gameState store.GameState) (store.GameState, error) {
+	if err := store.UpdateGameState(gameID, gameState); err != nil {
+		log.Println("[storeSeats] Failed to update game state: ", err)
+		return store.GameState{}, err
+	}
+	//the seat change was just stored so the row is as new as now
+	seated := gameState
+	seated.LastUpdate = time.Now().Unix()
+	indexLobby(gameID, seated)
+	log.Println("[storeSeats] Player seated: ", gameState)
+	return gameState, nil
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	store "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
+)
+
+// Status values. A finished game stores "<winner player UUID>:<reason>" or
+// "tied:<reason>". Games finished before reasons were stored hold just the
+// winner's UUID or "tied".
+const (
+	StatusActive = "active"
+	StatusTied   = "tied"
+)
+
+// ErrGameOver is returned for a change to a game that has already finished.
+var ErrGameOver = errors.New("game is over")
+
+// ErrCorrespondence is returned when abandoning a correspondence game. Its
+// players come and go between moves, so only the move deadline forfeits it.
+var ErrCorrespondence = errors.New("correspondence games are only forfeited on time")
+
+// Reason codes for how a game ended.
+const (
+	ReasonLine      = "line"       // a line was completed
+	ReasonBoardFull = "board_full" // no legal moves left
+	ReasonResigned  = "resigned"
+	ReasonAgreed    = "agreed"    // both players agreed to a draw
+	ReasonAbandoned = "abandoned" // a player disconnected and did not come back
+	ReasonForfeit   = "forfeit"   // a bot failed to move
+	ReasonTimeout   = "timeout"   // the side to move ran out of time
+)
+
+// EndStatus builds the stored status for a finished game; outcome is the
+// winner's player UUID or StatusTied.
+func EndStatus(outcome, reason string) string {
+	return outcome + ":" + reason
+}
+
+// End events tell a game's players how it ended when it was not decided by
+// a move. The websocket sends them as the gameEvent name.
+const (
+	EventResigned   = "resigned"
+	EventDrawAgreed = "draw_agreed"
+	EventAbandoned  = "abandoned"
+	EventTimeout    = "timeout"
+)
+
+// ParseStatus splits a stored status into the outcome (StatusActive,
+// StatusTied or the winner's UUID) and the reason code.
+func ParseStatus(status string) (outcome, reason string) {
+	if status == StatusActive {
+		return status, ""
+	}
+	if i := strings.LastIndex(status, ":"); i >= 0 {
+		return status[:i], status[i+1:]
+	}
+	if status == StatusTied {
+		return status, ReasonBoardFull
+	}
+	return status, ReasonLine
+}
+
+// Resign ends the game with playerID's opponent winning.
+func Resign(gameID, playerID string) (store.GameState, error) {
+	unlock := lockGame(gameID)
+	defer unlock()
+	gameState, err := store.GetGameState(gameID)
+	if err != nil {
+		log.Println("[Resign] Failed to get game state: ", err)
+		return store.GameState{}, err
+	}
+	opponent := Opponent(gameState, playerID)
+	if opponent == "" {
+		return store.GameState{}, errors.New("not a player in this game")
+	}
+	return endGame(gameID, gameState, EndStatus(opponent, ReasonResigned))
+}
+
+// AgreeDraw ends the game as a draw both players agreed to.
+func AgreeDraw(gameID string) (store.GameState, error) {
+	unlock := lockGame(gameID)
+	defer unlock()
+	gameState, err := store.GetGameState(gameID)
+	if err != nil {
+		log.Println("[AgreeDraw] Failed to get game state: ", err)
+		return store.GameState{}, err
+	}
+	return endGame(gameID, gameState, EndStatus(StatusTied, ReasonAgreed))
+}
+
+// Abandon ends the game in favour of whoever is still seated opposite playerID.
+// Correspondence games are left to their move deadline.
+func Abandon(gameID, playerID string) (store.GameState, error) {
+	unlock := lockGame(gameID)
+	defer unlock()
+	gameState, err := store.GetGameState(gameID)
+	if err != nil {
+		log.Println("[Abandon] Failed to get game state: ", err)
+		return store.GameState{}, err
+	}
+	opponent, err := abandonWinner(gameState, playerID)
+	if err != nil {
+		return store.GameState{}, err
+	}
+	return endGame(gameID, gameState, EndStatus(opponent, ReasonAbandoned))
+}
+
+// abandonWinner returns who wins gameState when playerID abandons it.
+func abandonWinner(gameState store.GameState, playerID string) (string, error) {
+	if gameState.Config.Correspondence {
+		return "", ErrCorrespondence
+	}
+	opponent := Opponent(gameState, playerID)
+	if opponent == "" {
+		return "", errors.New("no opponent to award the game to")
+	}
+	return opponent, nil
+}
+
+// endGame stores status for a game that is still active. gameState must be
+// the latest row, read while holding the game's lock.
+func endGame(gameID string, gameState store.GameState, status string) (store.GameState, error) {
+	if gameState.Status != StatusActive {
+		return store.GameState{}, ErrGameOver
+	}
+	if gameState.PlayerX == "" || gameState.PlayerO == "" {
+		return store.GameState{}, errors.New("game has not started")
+	}
+	gameState.Status = status
+	if err := store.UpdateGameState(gameID, gameState); err != nil {
+		log.Println("[EndGame] Failed to update game state: ", err)
+		return store.GameState{}, err
+	}
+	log.Println("[EndGame] Game ", gameID, " ended: ", status)
+	indexLobby(gameID, gameState)
+	rateGame(gameState)
+	return gameState, nil
+}
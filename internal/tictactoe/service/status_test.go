@@ -0,0 +1,38 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	store "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
+)
+
+func TestAbandonWinner(t *testing.T) {
+	seated := store.GameState{State: "x........o", Status: StatusActive, PlayerX: "px", PlayerO: "po"}
+	correspondence := seated
+	correspondence.Config = store.GameConfig{MoveTime: 86400, Correspondence: true}
+	timed := seated
+	timed.Config = store.GameConfig{MoveTime: 30}
+	tests := []struct {
+		name      string
+		gameState store.GameState
+		player    string
+		want      string
+		wantErr   bool
+	}{
+		{name: "x leaves", gameState: seated, player: "px", want: "po"},
+		{name: "o leaves", gameState: seated, player: "po", want: "px"},
+		{name: "timed game", gameState: timed, player: "px", want: "po"},
+		{name: "correspondence game", gameState: correspondence, player: "px", wantErr: true},
+		{name: "not seated", gameState: seated, player: "someone", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := abandonWinner(tt.gameState, tt.player)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%s: got %q, %v, want %q, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+		if tt.gameState.Config.Correspondence && !errors.Is(err, ErrCorrespondence) {
+			t.Errorf("%s: got error %v, want %v", tt.name, err, ErrCorrespondence)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"errors"
+	"log"
+
+	store "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
+)
+
+// Takeback undoes playerID's last move along with any reply made since,
+// by appending the earlier position as a new row. The undone rows stay in
+// the DB so the game's audit history is kept.
+func Takeback(gameID, playerID string) (store.GameState, error) {
+	unlock := lockGame(gameID)
+	defer unlock()
+	rows, err := store.GetGameHistory(gameID)
+	if err != nil {
+		log.Println("[Takeback] Failed to get game history: ", err)
+		return store.GameState{}, err
+	}
+	if len(rows) == 0 {
+		return store.GameState{}, errors.New("game not found")
+	}
+	gameState := rows[len(rows)-1]
+	if gameState.Status != StatusActive {
+		return store.GameState{}, ErrGameOver
+	}
+	if playerID != gameState.PlayerX && playerID != gameState.PlayerO {
+		return store.GameState{}, errors.New("not a player in this game")
+	}
+	target := -1
+	steps := Replay(rows)
+	for step := len(steps) - 1; step > 0; step-- {
+		if steps[step].Move.PlayerID == playerID {
+			target = step - 1
+			break
+		}
+	}
+	if target < 0 {
+		return store.GameState{}, errors.New("no move to take back")
+	}
+	log.Println("[Takeback] Rolling back game ", gameID, " from step ", len(steps)-1, " to ", target)
+	gameState.State = steps[target].GameState
+	if err := store.UpdateGameState(gameID, gameState); err != nil {
+		log.Println("[Takeback] Failed to update game state: ", err)
+		return store.GameState{}, err
+	}
+	return gameState, nil
+}
+
+// Opponent returns the player seated opposite playerID, or "" if playerID is not seated.
+func Opponent(gameState store.GameState, playerID string) string {
+	switch playerID {
+	case gameState.PlayerX:
+		return gameState.PlayerO
+	case gameState.PlayerO:
+		return gameState.PlayerX
+	}
+	return ""
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ultimateRules is ultimate tic-tac-toe: a 3x3 meta board whose cells are
+// classic boards. The cell played inside a sub-board sends the opponent to
+// the matching sub-board; if that one is already decided they may play in
+// any open sub-board. Winning three sub-boards in a row wins the game.
+//
+// State encoding (83 chars): 81 cells where sub-board b holds cells
+// b*9..b*9+8 (both numbered row by row), then the sub-board the next move
+// must be played in ('0'-'8', or '.' for any), then the turn marker.
+type ultimateRules struct{}
+
+const (
+	ultimateCells  = 81
+	ultimateForced = 81 // index of the forced sub-board marker
+)
+
+func (ultimateRules) InitialState() string {
+	return strings.Repeat(".", ultimateCells) + ".X"
+}
+
+func (ultimateRules) Cells() int { return ultimateCells }
+
+// ParseMove accepts a cell index ("x40") or a sub-board and cell ("x4,4").
+func (ultimateRules) ParseMove(move string) (byte, int, error) {
+	if len(move) < 2 {
+		return 0, 0, errors.New("malformed move: " + move)
+	}
+	piece := strings.ToLower(move[:1])[0]
+	if piece != 'x' && piece != 'o' {
+		return 0, 0, errors.New("malformed move: " + move)
+	}
+	if sub, cell, ok := strings.Cut(move[1:], ","); ok {
+		b, err1 := strconv.Atoi(sub)
+		c, err2 := strconv.Atoi(cell)
+		if err1 != nil || err2 != nil || b < 0 || b > 8 || c < 0 || c > 8 {
+			return 0, 0, errors.New("malformed move: " + move)
+		}
+		return piece, b*9 + c, nil
+	}
+	pos, err := strconv.Atoi(move[1:])
+	if err != nil || pos < 0 || pos >= ultimateCells {
+		return 0, 0, errors.New("malformed move: " + move)
+	}
+	return piece, pos, nil
+}
+
+func (u ultimateRules) LegalMoves(state []byte) []int {
+	var moves []int
+	for sub := 0; sub < 9; sub++ {
+		if forced := state[ultimateForced]; forced != '.' && int(forced-'0') != sub {
+			continue
+		}
+		if subBoardResult(state, sub) != 0 {
+			continue
+		}
+		for cell := sub * 9; cell < sub*9+9; cell++ {
+			if state[cell] == '.' {
+				moves = append(moves, cell)
+			}
+		}
+	}
+	return moves
+}
+
+func (ultimateRules) Place(state []byte, piece byte, pos int) {
+	state[pos] = piece
+	next := pos % 9
+	if subBoardResult(state, next) == 0 {
+		state[ultimateForced] = byte('0' + next)
+	} else {
+		state[ultimateForced] = '.'
+	}
+	passTurn(state)
+}
+
+// WinningLine returns the three sub-board indices (0-8) that form the
+// winning line on the meta board.
+func (ultimateRules) WinningLine(state []byte, pos int) ([]int, bool) {
+	piece := state[pos]
+	if subBoardResult(state, pos/9) != piece {
+		return nil, false
+	}
+	return ClassicBoard.lineThrough(metaBoard(state), piece, pos/9)
+}
+
+// subBoardResult is 'x' or 'o' if that side owns a line in the sub-board,
+// 'd' if it is full without one and 0 while it is still open.
+func subBoardResult(state []byte, sub int) byte {
+	cells := state[sub*9 : sub*9+9]
+	for _, piece := range []byte{'x', 'o'} {
+		if ClassicBoard.hasLine(cells, piece) {
+			return piece
+		}
+	}
+	for _, cell := range cells {
+		if cell == '.' {
+			return 0
+		}
+	}
+	return 'd'
+}
+
+// metaBoard is the 3x3 board of sub-board results.
+func metaBoard(state []byte) []byte {
+	meta := make([]byte, 9)
+	for sub := range meta {
+		meta[sub] = subBoardResult(state, sub)
+		if meta[sub] == 0 {
+			meta[sub] = '.'
+		}
+	}
+	return meta
+}
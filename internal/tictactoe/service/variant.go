@@ -0,0 +1,90 @@
+package service
+
+import (
+	store "github.com/Maiar0/tictactoe_backend/internal/tictactoe/store"
+)
+
+// Variant changes which pieces a side may place and who a completed line
+// counts for. The turn marker in the state always tracks the seat to move
+// (x or o); the piece placed is whatever the move names.
+type Variant struct {
+	Name           string `json:"name"`
+	Description    string `json:"description"`
+	completerLoses bool   // completing a line loses instead of wins
+	wild           bool   // either side may place x or o
+	shared         bool   // both sides place x
+}
+
+// Variant names selectable at creation.
+const (
+	VariantStandard = "standard"
+	VariantMisere   = "misere"
+	VariantWild     = "wild"
+	VariantNotakto  = "notakto"
+)
+
+var variants = []Variant{
+	{Name: VariantStandard, Description: "Complete a line to win."},
+	{Name: VariantMisere, Description: "Complete a line of your own pieces and you lose.", completerLoses: true},
+	{Name: VariantWild, Description: "Place either x or o; complete a line of either to win.", wild: true},
+	{Name: VariantNotakto, Description: "Both sides place x; whoever completes a line loses.", completerLoses: true, shared: true},
+}
+
+// Variants lists every rule variant.
+func Variants() []Variant {
+	return append([]Variant(nil), variants...)
+}
+
+// IsVariant reports whether name is a known variant.
+func IsVariant(name string) bool {
+	_, ok := lookupVariant(name)
+	return ok
+}
+
+// VariantFor returns the variant stored in the game's config; unknown or
+// missing names fall back to standard.
+func VariantFor(config store.GameConfig) Variant {
+	if v, ok := lookupVariant(config.Variant); ok {
+		return v
+	}
+	return variants[0]
+}
+
+func lookupVariant(name string) (Variant, bool) {
+	for _, v := range variants {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return Variant{}, false
+}
+
+// Pieces returns the pieces the side to move may place.
+func (v Variant) Pieces(turn byte) []byte {
+	switch {
+	case v.wild:
+		return []byte{'x', 'o'}
+	case v.shared:
+		return []byte{'x'}
+	default:
+		return []byte{turn}
+	}
+}
+
+// allows reports whether side may place piece.
+func (v Variant) allows(side, piece byte) bool {
+	for _, p := range v.Pieces(side) {
+		if p == piece {
+			return true
+		}
+	}
+	return false
+}
+
+// Winner returns the side that wins when mover completes a line.
+func (v Variant) Winner(mover byte) byte {
+	if v.completerLoses {
+		return otherPiece(mover)
+	}
+	return mover
+}
@@ -0,0 +1,29 @@
+package service
+
+import "testing"
+
+func TestVariantWinner(t *testing.T) {
+	tests := []struct {
+		variant string
+		mover   byte
+		want    byte
+	}{
+		{VariantStandard, 'x', 'x'},
+		{VariantStandard, 'o', 'o'},
+		{VariantMisere, 'x', 'o'},
+		{VariantMisere, 'o', 'x'},
+		{VariantWild, 'x', 'x'},
+		{VariantWild, 'o', 'o'},
+		{VariantNotakto, 'x', 'o'},
+		{VariantNotakto, 'o', 'x'},
+	}
+	for _, tt := range tests {
+		variant, ok := lookupVariant(tt.variant)
+		if !ok {
+			t.Fatalf("unknown variant %q", tt.variant)
+		}
+		if got := variant.Winner(tt.mover); got != tt.want {
+			t.Errorf("%s: %c completed a line, got winner %c, want %c", tt.variant, tt.mover, got, tt.want)
+		}
+	}
+}
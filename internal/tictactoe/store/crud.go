@@ -40,6 +40,11 @@ func (g *GameStore) ReadGameState(field string, values ...any) (*sql.Rows, error
 	return g.db.Query(fmt.Sprintf("SELECT * FROM game WHERE %s = ?", field), values[0])
 }
 
+// ReadGameHistory returns every game row oldest first
+func (g *GameStore) ReadGameHistory() (*sql.Rows, error) {
+	return g.db.Query("SELECT * FROM game ORDER BY id")
+}
+
 // UpdateGameState updates columns in rows that match field=value
 func (g *GameStore) UpdateGameState(field string, value any, updates map[string]any) (sql.Result, error) {
 	setClause := ""
@@ -61,3 +66,29 @@ func (g *GameStore) DeleteGameState(field string, value any) (sql.Result, error)
 	query := fmt.Sprintf("DELETE FROM game WHERE %s = ?", field)
 	return g.db.Exec(query, value)
 }
+
+// SetConfig stores a single game setting, replacing any previous value for the key
+func (g *GameStore) SetConfig(key, value string) (sql.Result, error) {
+	return g.db.Exec(`
+        INSERT INTO config (key, value) VALUES (?, ?)
+        ON CONFLICT(key) DO UPDATE SET value = excluded.value
+    `, key, value)
+}
+
+// ReadConfig returns every game setting as a key/value map
+func (g *GameStore) ReadConfig() (map[string]string, error) {
+	rows, err := g.db.Query("SELECT key, value FROM config")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	config := map[string]string{}
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		config[key] = value
+	}
+	return config, rows.Err()
+}
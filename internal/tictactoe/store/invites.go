@@ -0,0 +1,112 @@
+package store
+
+import (
+	"database/sql"
+	"log"
+
+	sqlite "github.com/Maiar0/tictactoe_backend/internal/store"
+)
+
+const (
+	invitesDir        = "Storage/invites"
+	invitesSchemaPath = "internal/tictactoe/store/invites.sql"
+)
+
+// Invite lets one other player take the free seat in a game. It can be
+// redeemed once, by its short Code or by the longer Token used in links.
+type Invite struct {
+	Code      string `db:"code"`
+	Token     string `db:"token"`
+	GameID    string `db:"game_id"`
+	CreatorID string `db:"creator"`
+	CreatedAt int64  `db:"created_at"`
+	ExpiresAt int64  `db:"expires_at"`
+	Used      bool   `db:"used"`
+	Revoked   bool   `db:"revoked"`
+}
+
+// invites are kept in one DB shared by every game so codes can be looked up
+func openInvites() (*sql.DB, error) {
+	return sqlite.New(invitesDir).OpenFor("invites", invitesSchemaPath)
+}
+
+// CreateInvite stores a new invite.
+func CreateInvite(invite Invite) error {
+	db, err := openInvites()
+	if err != nil {
+		log.Println("[CreateInvite] Failed to open DB: ", err)
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec(`
+        INSERT INTO invite (code, token, game_id, creator, created_at, expires_at)
+        VALUES (?, ?, ?, ?, ?, ?)
+    `, invite.Code, invite.Token, invite.GameID, invite.CreatorID, invite.CreatedAt, invite.ExpiresAt)
+	if err != nil {
+		log.Println("[CreateInvite] Failed to create invite: ", err)
+		return err
+	}
+	log.Println("[CreateInvite] Invite created: ", invite.Code, " for game ", invite.GameID)
+	return nil
+}
+
+// GetInvite finds an invite by its code or link token.
+// Returns sql.ErrNoRows if there is none.
+func GetInvite(codeOrToken string) (Invite, error) {
+	var invite Invite
+	db, err := openInvites()
+	if err != nil {
+		log.Println("[GetInvite] Failed to open DB: ", err)
+		return invite, err
+	}
+	defer db.Close()
+	err = db.QueryRow(`
+        SELECT code, token, game_id, creator, created_at, expires_at, used, revoked
+        FROM invite WHERE code = ? OR token = ?
+    `, codeOrToken, codeOrToken).Scan(&invite.Code, &invite.Token, &invite.GameID, &invite.CreatorID,
+		&invite.CreatedAt, &invite.ExpiresAt, &invite.Used, &invite.Revoked)
+	return invite, err
+}
+
+// UseInvite marks an invite used. ok is false if it was already used or
+// revoked, so only one caller can ever redeem it.
+func UseInvite(code string) (ok bool, err error) {
+	return markInvite(code, "used")
+}
+
+// ReleaseInvite makes a used invite redeemable again, for when the player
+// who used it could not be seated.
+func ReleaseInvite(code string) error {
+	db, err := openInvites()
+	if err != nil {
+		log.Println("[ReleaseInvite] Failed to open DB: ", err)
+		return err
+	}
+	defer db.Close()
+	if _, err := db.Exec("UPDATE invite SET used = 0 WHERE code = ? AND used = 1", code); err != nil {
+		log.Println("[ReleaseInvite] Failed to release invite: ", err)
+		return err
+	}
+	return nil
+}
+
+// RevokeInvite marks an invite revoked. ok is false if it was already used or revoked.
+func RevokeInvite(code string) (ok bool, err error) {
+	return markInvite(code, "revoked")
+}
+
+func markInvite(code, column string) (bool, error) {
+	db, err := openInvites()
+	if err != nil {
+		log.Println("[markInvite] Failed to open DB: ", err)
+		return false, err
+	}
+	defer db.Close()
+	res, err := db.Exec("UPDATE invite SET "+column+" = 1 WHERE code = ? AND used = 0 AND revoked = 0", code)
+	if err != nil {
+		log.Println("[markInvite] Failed to mark invite ", column, ": ", err)
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS invite(
+		code TEXT PRIMARY KEY,
+		token TEXT NOT NULL UNIQUE,
+		game_id TEXT NOT NULL,
+		creator TEXT NOT NULL,
+		created_at INTEGER NOT NULL,
+		expires_at INTEGER NOT NULL,
+		used INTEGER NOT NULL DEFAULT 0,
+		revoked INTEGER NOT NULL DEFAULT 0
+	);
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"database/sql"
+	"log"
+
+	sqlite "github.com/Maiar0/tictactoe_backend/internal/store"
+)
+
+const (
+	ratingsDir        = "Storage/ratings"
+	ratingsSchemaPath = "internal/tictactoe/store/ratings.sql"
+)
+
+// Rating is a player's rating from the rated games they have finished.
+type Rating struct {
+	PlayerID string `db:"player_id"`
+	Rating   int    `db:"rating"`
+	Games    int    `db:"games"`
+}
+
+// ratings are kept in one DB shared by every game, keyed by player
+func openRatings() (*sql.DB, error) {
+	return sqlite.New(ratingsDir).OpenFor("ratings", ratingsSchemaPath)
+}
+
+// GetRating returns a player's rating. Returns sql.ErrNoRows if they have none.
+func GetRating(playerID string) (Rating, error) {
+	rating := Rating{PlayerID: playerID}
+	db, err := openRatings()
+	if err != nil {
+		log.Println("[GetRating] Failed to open DB: ", err)
+		return rating, err
+	}
+	defer db.Close()
+	err = db.QueryRow(`SELECT rating, games FROM rating WHERE player_id = ?`, playerID).Scan(&rating.Rating, &rating.Games)
+	return rating, err
+}
+
+// SaveRatings stores the given ratings together, replacing earlier ones.
+func SaveRatings(ratings ...Rating) error {
+	db, err := openRatings()
+	if err != nil {
+		log.Println("[SaveRatings] Failed to open DB: ", err)
+		return err
+	}
+	defer db.Close()
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, r := range ratings {
+		_, err := tx.Exec(`
+            INSERT INTO rating (player_id, rating, games) VALUES (?, ?, ?)
+            ON CONFLICT(player_id) DO UPDATE SET rating = excluded.rating, games = excluded.games
+        `, r.PlayerID, r.Rating, r.Games)
+		if err != nil {
+			log.Println("[SaveRatings] Failed to save rating: ", err)
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
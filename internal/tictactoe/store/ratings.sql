@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS rating(
+		player_id TEXT PRIMARY KEY,
+		rating INTEGER NOT NULL,
+		games INTEGER NOT NULL DEFAULT 0
+	);
@@ -5,4 +5,8 @@ CREATE TABLE IF NOT EXISTS game(
 		player_o TEXT,
 		last_update INTEGER NOT NULL,
 		status TEXT
-	)
\ No newline at end of file
+	);
+CREATE TABLE IF NOT EXISTS config(
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);
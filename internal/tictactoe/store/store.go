@@ -2,16 +2,18 @@ package store
 
 import (
 	"crypto/rand"
+	"errors"
 	"log"
 	"math/big"
+	"strconv"
 
 	sqlite "github.com/Maiar0/tictactoe_backend/internal/store"
 )
 
+// Every game is stored under baseDir whatever its mode.
 const (
-	baseDir      = "Storage/games/tictactoe"
-	schemaPath   = "internal/tictactoe/store/schema.sql"
-	initialState = ".........X" //9 blank squares last value is whose turn it is WIP
+	baseDir    = "Storage/games/tictactoe"
+	schemaPath = "internal/tictactoe/store/schema.sql"
 )
 
 type GameState struct {
@@ -21,6 +23,92 @@ type GameState struct {
 	PlayerO    string `db:"player_two"`
 	LastUpdate int64  `db:"last_update"`
 	Status     string `db:"status"`
+	Config     GameConfig
+}
+
+// GameConfig holds the settings chosen when the game was created.
+// It lives in the config table and is not part of the append-only game rows.
+type GameConfig struct {
+	IsAi    bool
+	Bot     string // registered bot name that plays the computer side
+	Mode    string // classic, ultimate or qubic
+	Variant string // standard, misere, wild or notakto
+	Rows    int
+	Cols    int
+	K       int // pieces in a row needed to win
+	// time control in seconds; MoveTime and BaseTime are never both set
+	MoveTime  int // per move
+	BaseTime  int // per side for the whole game
+	Increment int // added to BaseTime clocks after each move
+	// correspondence games have MoveTime deadlines of hours or days that
+	// are enforced by a background scheduler rather than in-memory timers
+	Correspondence bool
+	// rematches link games into a series; BestOf 0 is a single game
+	BestOf       int
+	SeriesGame   int    // 1 for the first game of a series
+	PreviousGame string // game this one is a rematch of
+	NextGame     string // rematch of this game once created
+	// rated games change both players' ratings when they finish
+	Rated bool
+}
+
+func (c GameConfig) toMap() map[string]string {
+	return map[string]string{
+		"is_ai":          strconv.FormatBool(c.IsAi),
+		"bot":            c.Bot,
+		"mode":           c.Mode,
+		"variant":        c.Variant,
+		"rows":           strconv.Itoa(c.Rows),
+		"cols":           strconv.Itoa(c.Cols),
+		"k":              strconv.Itoa(c.K),
+		"move_time":      strconv.Itoa(c.MoveTime),
+		"base_time":      strconv.Itoa(c.BaseTime),
+		"increment":      strconv.Itoa(c.Increment),
+		"correspondence": strconv.FormatBool(c.Correspondence),
+		"best_of":        strconv.Itoa(c.BestOf),
+		"series_game":    strconv.Itoa(c.SeriesGame),
+		"previous_game":  c.PreviousGame,
+		"next_game":      c.NextGame,
+		"rated":          strconv.FormatBool(c.Rated),
+	}
+}
+
+func configFromMap(m map[string]string) GameConfig {
+	var c GameConfig
+	c.IsAi, _ = strconv.ParseBool(m["is_ai"])
+	c.Bot = m["bot"]
+	if c.Bot == "" {
+		c.Bot = m["ai_level"] // games created before bots were registered by name
+	}
+	c.Mode = m["mode"]
+	if c.Mode == "" {
+		c.Mode = "classic"
+	}
+	c.Variant = m["variant"]
+	if c.Variant == "" {
+		c.Variant = "standard"
+	}
+	// games created before board sizes were configurable are 3x3
+	c.Rows = intOr(m["rows"], 3)
+	c.Cols = intOr(m["cols"], 3)
+	c.K = intOr(m["k"], 3)
+	c.MoveTime = intOr(m["move_time"], 0)
+	c.BaseTime = intOr(m["base_time"], 0)
+	c.Increment = intOr(m["increment"], 0)
+	c.Correspondence, _ = strconv.ParseBool(m["correspondence"])
+	c.BestOf = intOr(m["best_of"], 0)
+	c.SeriesGame = intOr(m["series_game"], 1)
+	c.PreviousGame = m["previous_game"]
+	c.NextGame = m["next_game"]
+	c.Rated, _ = strconv.ParseBool(m["rated"])
+	return c
+}
+
+func intOr(s string, fallback int) int {
+	if n, err := strconv.Atoi(s); err == nil && n > 0 {
+		return n
+	}
+	return fallback
 }
 
 func newGameID() string { //TODO:: huh
@@ -34,7 +122,12 @@ func newGameID() string { //TODO:: huh
 	return string(b)
 }
 
-func NewGame() (string, error) {
+// ErrGameNotFound is returned when writing to or reading the state of a game
+// that has no DB file. Opening one would create it.
+var ErrGameNotFound = errors.New("game not found")
+
+// NewGame creates a game DB seeded with initialState and the given config.
+func NewGame(config GameConfig, initialState string) (string, error) {
 	log.Println("[NewGame] Starting new game creation: ", baseDir, " : ", schemaPath, " : ", initialState)
 	id := newGameID()
 	log.Println("[NewGame] Generated game ID", id)
@@ -55,13 +148,63 @@ func NewGame() (string, error) {
 	}
 	insertID, _ := res.LastInsertId() // return PK
 	log.Println("[NewGame] Game inserted succesfully. Insert ID: ", insertID)
+	for key, value := range config.toMap() {
+		if _, err := gameStore.SetConfig(key, value); err != nil {
+			log.Println("[NewGame] Failed to store config: ", err)
+			return "", err
+		}
+	}
 	return id, nil
 }
 
+// UpdateConfig replaces the stored config of a game.
+func UpdateConfig(gameID string, config GameConfig) error {
+	log.Println("[UpdateConfig] Updating config for game ID: ", gameID)
+	st := sqlite.New(baseDir)
+	if !st.Exists(gameID) {
+		log.Println("[UpdateConfig] No such game: ", gameID)
+		return ErrGameNotFound
+	}
+	db, err := st.OpenFor(gameID, schemaPath)
+	if err != nil {
+		log.Println("[UpdateConfig] Failed to open DB: ", err)
+		return err
+	}
+	defer db.Close()
+	gameStore := NewGameStore(db)
+	for key, value := range config.toMap() {
+		if _, err := gameStore.SetConfig(key, value); err != nil {
+			log.Println("[UpdateConfig] Failed to store config: ", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// Exists reports whether a game is stored, without creating its DB file.
+func Exists(gameID string) bool {
+	return sqlite.New(baseDir).Exists(gameID)
+}
+
+// ListGames returns the IDs of every stored game.
+func ListGames() ([]string, error) {
+	ids, err := sqlite.New(baseDir).GameIDs()
+	if err != nil {
+		log.Println("[ListGames] Failed to list games: ", err)
+		return nil, err
+	}
+	return ids, nil
+}
+
+// GetGameState returns the latest state row of a game along with its config.
 func GetGameState(gameID string) (GameState, error) {
 	var gameState GameState
 	log.Println("[GetGameState] Getting game state for game ID: ", gameID)
 	st := sqlite.New(baseDir)
+	if !st.Exists(gameID) {
+		log.Println("[GetGameState] No such game: ", gameID)
+		return gameState, ErrGameNotFound
+	}
 	db, err := st.OpenFor(gameID, schemaPath)
 	if err != nil {
 		log.Println("[GetGameState] Failed to open DB: ", err)
@@ -88,18 +231,30 @@ func GetGameState(gameID string) (GameState, error) {
 		}
 	}
 	log.Println("[GetGameState] ID: ", highestID, " State: ", gameState.State)
+	config, err := gameStore.ReadConfig()
+	if err != nil {
+		log.Println("[GetGameState] Failed to read config: ", err)
+		return gameState, err
+	}
+	gameState.Config = configFromMap(config)
 	defer db.Close()
 	return gameState, nil
 }
 
+// UpdateGameState appends gameState as the newest row; earlier rows are kept as history.
 func UpdateGameState(gameID string, gameState GameState) error {
 	log.Println("[UpdateGameState] Updating game state for game ID: ", gameID)
 	st := sqlite.New(baseDir)
+	if !st.Exists(gameID) {
+		log.Println("[UpdateGameState] No such game: ", gameID)
+		return ErrGameNotFound
+	}
 	db, err := st.OpenFor(gameID, schemaPath)
 	if err != nil {
 		log.Println("[UpdateGameState] Failed to open DB: ", err)
 		return err
 	}
+	defer db.Close()
 	log.Println("[UpdateGameState] DB Opened succesfully: ", gameID)
 	gameStore := NewGameStore(db)
 	_, err = gameStore.CreateGameState(gameState.State, gameState.PlayerX, gameState.PlayerO, gameState.Status)
@@ -110,3 +265,45 @@ func UpdateGameState(gameID string, gameState GameState) error {
 	log.Println("[UpdateGameState] Game state updated succesfully: ", gameState)
 	return nil
 }
+
+// GetGameHistory returns every state row of a game oldest first, each with the game's config.
+// Rows are only ever appended so this is the full record of the game.
+// A game with no DB file has no rows.
+func GetGameHistory(gameID string) ([]GameState, error) {
+	log.Println("[GetGameHistory] Getting game history for game ID: ", gameID)
+	st := sqlite.New(baseDir)
+	if !st.Exists(gameID) {
+		log.Println("[GetGameHistory] No such game: ", gameID)
+		return nil, nil
+	}
+	db, err := st.OpenFor(gameID, schemaPath)
+	if err != nil {
+		log.Println("[GetGameHistory] Failed to open DB: ", err)
+		return nil, err
+	}
+	defer db.Close()
+	gameStore := NewGameStore(db)
+	config, err := gameStore.ReadConfig()
+	if err != nil {
+		log.Println("[GetGameHistory] Failed to read config: ", err)
+		return nil, err
+	}
+	rows, err := gameStore.ReadGameHistory()
+	if err != nil {
+		log.Println("[GetGameHistory] Failed to read game history: ", err)
+		return nil, err
+	}
+	defer rows.Close()
+	var history []GameState
+	for rows.Next() {
+		var state GameState
+		if err := rows.Scan(&state.ID, &state.State, &state.PlayerX, &state.PlayerO, &state.LastUpdate, &state.Status); err != nil {
+			log.Println("[GetGameHistory] Failed to scan row: ", err)
+			return nil, err
+		}
+		state.Config = configFromMap(config)
+		history = append(history, state)
+	}
+	log.Println("[GetGameHistory] Rows: ", len(history))
+	return history, rows.Err()
+}
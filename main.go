@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	tttApi "github.com/Maiar0/tictactoe_backend/internal/tictactoe/api"
+	tttService "github.com/Maiar0/tictactoe_backend/internal/tictactoe/service"
 	utils "github.com/Maiar0/tictactoe_backend/internal/utils"
 )
 
@@ -20,6 +21,10 @@ func main() {
 		w.Write([]byte("ok"))
 	})
 	tttApi.Register(mux)
+	if err := tttService.LoadExternalBots("bots"); err != nil {
+		log.Println("[Main] Failed to load external bots: ", err)
+	}
+	tttApi.StartCorrespondence(&tttService.LogNotifier{Path: "Storage/notices.log"})
 
 	// Serve static files test cases
 	mux.HandleFunc("/test/together", func(w http.ResponseWriter, r *http.Request) {